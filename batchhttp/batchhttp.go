@@ -0,0 +1,135 @@
+// Package batchhttp serves a dataloader over HTTP, and fetches from one, so
+// that loaders can be chained across process boundaries.
+//
+// The protocol is JSON over HTTP. The client POSTs the keys it wants:
+//
+//	{"keys": ["urn:isbn:978-1098118730", "urn:isbn:fake-book"]}
+//
+// and the server responds with one result for each key, which has either a
+// value or an error:
+//
+//	{"results": [
+//	  {"key": "urn:isbn:978-1098118730", "value": {"id": "urn:isbn:978-1098118730", "title": "..."}},
+//	  {"key": "urn:isbn:fake-book", "error": {"code": "not_found", "message": "not found (urn:isbn:fake-book)"}}
+//	]}
+//
+// The error code is the kind of error, as httperr, grpcerr and gqlerr report
+// it, so a Fetcher can fail each key the same way it failed on the server. As
+// with those packages, the message is only sent if errclass.Exposed allows it.
+//
+// Keys and values may be any type that can be encoded as JSON.
+package batchhttp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jsocol/dataloader"
+)
+
+// Error codes, one for each kind of error.
+const (
+	CodeNotFound    = "not_found"
+	CodeTimeout     = "timeout"
+	CodeCanceled    = "canceled"
+	CodeOverloaded  = "overloaded"
+	CodeUnavailable = "unavailable"
+	CodePanic       = "panic"
+	CodeInternal    = "internal"
+)
+
+const (
+	// DefaultMaxKeys is the most keys a Handler accepts in one request when
+	// WithMaxKeys is not used.
+	DefaultMaxKeys = 1000
+
+	// DefaultMaxBodySize is the most either side will read from a request or
+	// response body when WithMaxBodySize is not used.
+	DefaultMaxBodySize = 4 << 20
+)
+
+type request[K comparable] struct {
+	Keys []K `json:"keys"`
+}
+
+type response[K comparable, V any] struct {
+	Results []result[K, V] `json:"results"`
+}
+
+type result[K comparable, V any] struct {
+	Key   K            `json:"key"`
+	Value *V           `json:"value,omitempty"`
+	Error *RemoteError `json:"error,omitempty"`
+}
+
+// RemoteError is an error for a single key, as reported by a Handler. It
+// wraps the error its code stands for, if there is one, like
+// dataloader.ErrOverloaded for CodeOverloaded, and reports whether it is a
+// timeout, so it can be handled like the error on the server.
+type RemoteError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *RemoteError) Error() string {
+	return fmt.Sprintf("remote error: %s", e.Message)
+}
+
+func (e *RemoteError) Unwrap() error {
+	switch e.Code {
+	case CodeCanceled:
+		return context.Canceled
+	case CodeOverloaded:
+		return dataloader.ErrOverloaded
+	case CodeUnavailable:
+		return dataloader.ErrCircuitOpen
+	}
+	return nil
+}
+
+// Timeout reports whether the key timed out on the server.
+func (e *RemoteError) Timeout() bool {
+	return e.Code == CodeTimeout
+}
+
+type config struct {
+	client      Doer
+	maxKeys     int
+	maxBodySize int64
+}
+
+type Option func(*config)
+
+// WithClient sets the client a Fetcher uses to make requests. The default is
+// http.DefaultClient.
+func WithClient(client Doer) Option {
+	return func(c *config) {
+		c.client = client
+	}
+}
+
+// WithMaxKeys sets the most keys a Handler accepts in one request.
+func WithMaxKeys(n int) Option {
+	return func(c *config) {
+		c.maxKeys = n
+	}
+}
+
+// WithMaxBodySize sets the most a Handler reads from a request, or a Fetcher
+// reads from a response.
+func WithMaxBodySize(n int64) Option {
+	return func(c *config) {
+		c.maxBodySize = n
+	}
+}
+
+func newConfig(opts []Option) config {
+	c := config{
+		maxKeys:     DefaultMaxKeys,
+		maxBodySize: DefaultMaxBodySize,
+	}
+	for _, o := range opts {
+		o(&c)
+	}
+	return c
+}
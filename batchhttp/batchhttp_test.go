@@ -0,0 +1,167 @@
+package batchhttp_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jsocol/dataloader"
+	"github.com/jsocol/dataloader/batchhttp"
+	"github.com/jsocol/dataloader/httperr"
+	"github.com/jsocol/dataloader/httpfetch"
+)
+
+type book struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+var errUnavailable = errors.New("shard unavailable")
+
+func TestRoundTrip(t *testing.T) {
+	var calls atomic.Int64
+	upstream := dataloader.New(func(ids []string) (map[string]book, error) {
+		calls.Add(1)
+		ret := make(map[string]book)
+		keyErrs := make(dataloader.KeyErrors[string])
+		for _, id := range ids {
+			switch id {
+			case "urn:isbn:978-1098118730":
+				ret[id] = book{ID: id, Title: "The Staff Engineer's Path"}
+			case "urn:isbn:broken":
+				keyErrs[id] = errUnavailable
+			}
+		}
+		return ret, keyErrs
+	})
+
+	srv := httptest.NewServer(batchhttp.NewHandler(upstream))
+	defer srv.Close()
+
+	l := dataloader.New(batchhttp.NewFetcher[string, book](srv.URL).Fetch)
+
+	var wg sync.WaitGroup
+	wg.Add(3)
+
+	go func() {
+		defer wg.Done()
+		b, err := l.Load("urn:isbn:978-1098118730")
+		assert.NoError(t, err)
+		assert.Equal(t, "The Staff Engineer's Path", b.Title)
+	}()
+
+	go func() {
+		defer wg.Done()
+		_, err := l.Load("urn:isbn:fake-book")
+		assert.ErrorIs(t, err, dataloader.ErrNotFound)
+	}()
+
+	go func() {
+		defer wg.Done()
+		_, err := l.Load("urn:isbn:broken")
+		var rErr *batchhttp.RemoteError
+		assert.ErrorAs(t, err, &rErr)
+		assert.Equal(t, batchhttp.CodeInternal, rErr.Code)
+		assert.Equal(t, "internal error", rErr.Message)
+	}()
+
+	wg.Wait()
+
+	assert.Equal(t, int64(1), calls.Load())
+}
+
+func TestRoundTripErrorCodes(t *testing.T) {
+	upstream := dataloader.New(func(ids []string) (map[string]book, error) {
+		return nil, dataloader.KeyErrors[string]{
+			"urn:isbn:busy":  fmt.Errorf("shard: %w", dataloader.ErrOverloaded),
+			"urn:isbn:slow":  &dataloader.TimeoutError{Op: "fetch", After: time.Second},
+			"urn:isbn:panic": &dataloader.PanicError{Value: "secret upstream detail"},
+		}
+	})
+
+	srv := httptest.NewServer(batchhttp.NewHandler(upstream))
+	defer srv.Close()
+
+	f := batchhttp.NewFetcher[string, book](srv.URL)
+	_, err := f.Fetch([]string{"urn:isbn:busy", "urn:isbn:slow", "urn:isbn:panic"})
+
+	var keyErrs dataloader.KeyErrors[string]
+	if !assert.ErrorAs(t, err, &keyErrs) {
+		return
+	}
+
+	// remote errors are handled like the errors they stand for
+	assert.ErrorIs(t, keyErrs["urn:isbn:busy"], dataloader.ErrOverloaded)
+	assert.Equal(t, http.StatusGatewayTimeout, httperr.Code(keyErrs["urn:isbn:slow"]))
+
+	var rErr *batchhttp.RemoteError
+	if assert.ErrorAs(t, keyErrs["urn:isbn:panic"], &rErr) {
+		assert.Equal(t, batchhttp.CodePanic, rErr.Code)
+		assert.NotContains(t, rErr.Message, "secret")
+	}
+}
+
+func TestHandlerErrors(t *testing.T) {
+	upstream := dataloader.New(func(ids []string) (map[string]book, error) {
+		return nil, nil
+	})
+
+	h := batchhttp.NewHandler(upstream, batchhttp.WithMaxKeys(1))
+
+	tests := []struct {
+		method string
+		body   string
+		status int
+	}{
+		{http.MethodGet, "", http.StatusMethodNotAllowed},
+		{http.MethodPost, "{", http.StatusBadRequest},
+		{http.MethodPost, `{"keys": ["a", "b"]}`, http.StatusRequestEntityTooLarge},
+		{http.MethodPost, `{"keys": ["a"]}`, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(tt.method, "/", strings.NewReader(tt.body)))
+		assert.Equal(t, tt.status, w.Code, "%s %s", tt.method, tt.body)
+	}
+}
+
+func TestFetcherContext(t *testing.T) {
+	var calls atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer srv.Close()
+
+	f := batchhttp.NewFetcher[string, book](srv.URL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := f.FetchContext(ctx, []string{"urn:isbn:978-1098118730"})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Zero(t, calls.Load())
+}
+
+func TestFetcherStatusError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "upstream unavailable", http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	f := batchhttp.NewFetcher[string, book](srv.URL)
+	_, err := f.Fetch([]string{"urn:isbn:978-1098118730"})
+
+	var sErr *httpfetch.StatusError
+	assert.ErrorAs(t, err, &sErr)
+	assert.Equal(t, http.StatusBadGateway, sErr.StatusCode)
+}
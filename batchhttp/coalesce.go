@@ -0,0 +1,55 @@
+package batchhttp
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/jsocol/dataloader"
+	"github.com/jsocol/dataloader/httperr"
+)
+
+// Coalesce is middleware for single-resource endpoints, like GET /things/{id}.
+// It serves GET and HEAD requests from src, which is usually a
+// *dataloader.Loader, so that concurrent requests for different keys are
+// fetched in one batch and the results fanned back out, without changing any
+// clients.
+//
+// key extracts the key from a request. If it returns false, or the method is
+// not GET or HEAD, the request is passed to the next handler instead.
+//
+// Values are written as JSON, and errors are written with httperr.Write, so
+// keys that are not found get a 404.
+func Coalesce[K comparable, V any](src dataloader.Source[K, V], key func(*http.Request) (K, bool)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			k, ok := key(r)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			v, err := src.LoadContext(r.Context(), k)
+			if err != nil {
+				httperr.Write(w, err)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(v)
+		})
+	}
+}
+
+// PathValue returns a key function for Coalesce that uses a wildcard from the
+// request's ServeMux pattern, like {id} in "GET /things/{id}".
+func PathValue(name string) func(*http.Request) (string, bool) {
+	return func(r *http.Request) (string, bool) {
+		v := r.PathValue(name)
+		return v, v != ""
+	}
+}
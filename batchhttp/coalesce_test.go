@@ -0,0 +1,111 @@
+package batchhttp_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jsocol/dataloader"
+	"github.com/jsocol/dataloader/batchhttp"
+)
+
+func TestCoalesce(t *testing.T) {
+	var calls atomic.Int64
+	books := dataloader.New(func(ids []string) (map[string]book, error) {
+		calls.Add(1)
+		assert.ElementsMatch(t, []string{"urn:isbn:978-1098118730", "urn:isbn:978-1098149482", "urn:isbn:fake-book"}, ids)
+		return map[string]book{
+			"urn:isbn:978-1098118730": {ID: "urn:isbn:978-1098118730", Title: "The Staff Engineer's Path"},
+			"urn:isbn:978-1098149482": {ID: "urn:isbn:978-1098149482", Title: "The Engineering Executive's Primer"},
+		}, nil
+	}, dataloader.WithDelay(50*time.Millisecond))
+
+	// the legacy handler still serves everything else
+	legacy := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	mux := http.NewServeMux()
+	mux.Handle("/books/{id}", batchhttp.Coalesce(books, batchhttp.PathValue("id"))(legacy))
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	get := func(id string) (*http.Response, book) {
+		resp, err := http.Get(srv.URL + "/books/" + id)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+
+		var b book
+		if resp.StatusCode == http.StatusOK {
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(&b))
+		}
+		return resp, b
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(4)
+
+	go func() {
+		defer wg.Done()
+		resp, b := get("urn:isbn:978-1098118730")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "The Staff Engineer's Path", b.Title)
+	}()
+
+	go func() {
+		defer wg.Done()
+		resp, b := get("urn:isbn:978-1098118730")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "The Staff Engineer's Path", b.Title)
+	}()
+
+	go func() {
+		defer wg.Done()
+		resp, b := get("urn:isbn:978-1098149482")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "The Engineering Executive's Primer", b.Title)
+	}()
+
+	go func() {
+		defer wg.Done()
+		resp, _ := get("urn:isbn:fake-book")
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	}()
+
+	wg.Wait()
+
+	assert.Equal(t, int64(1), calls.Load())
+
+	resp, err := http.Post(srv.URL+"/books/urn:isbn:978-1098118730", "application/json", nil)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusTeapot, resp.StatusCode)
+}
+
+func TestCoalesceRequestContext(t *testing.T) {
+	var calls atomic.Int64
+	books := dataloader.New(func(ids []string) (map[string]book, error) {
+		calls.Add(1)
+		return nil, nil
+	}, dataloader.WithDelay(time.Hour))
+
+	h := batchhttp.Coalesce(books, batchhttp.PathValue("id"))(http.NotFoundHandler())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// the client has gone away, so the handler stops waiting on the batch
+	req := httptest.NewRequest(http.MethodGet, "/books/urn:isbn:978-1098118730", nil).WithContext(ctx)
+	req.SetPathValue("id", "urn:isbn:978-1098118730")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Zero(t, calls.Load())
+}
@@ -0,0 +1,114 @@
+package batchhttp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/jsocol/dataloader"
+	"github.com/jsocol/dataloader/httpfetch"
+)
+
+// Doer is implemented by *http.Client.
+type Doer interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+// Fetcher loads keys from a Handler in another process. Its Fetch method can
+// be passed to dataloader.New, and its FetchContext method to
+// dataloader.NewContext.
+type Fetcher[K comparable, V any] struct {
+	url    string
+	config config
+}
+
+// NewFetcher creates a Fetcher for the Handler at url.
+func NewFetcher[K comparable, V any](url string, opts ...Option) *Fetcher[K, V] {
+	c := newConfig(opts)
+	if c.client == nil {
+		c.client = http.DefaultClient
+	}
+	return &Fetcher[K, V]{
+		url:    url,
+		config: c,
+	}
+}
+
+// Fetch requests keys from the Handler. Keys the Handler reports as not found
+// are left out of the results, so the Loader reports them as not found too,
+// and keys that failed are returned as dataloader.KeyErrors of *RemoteError.
+// A response with a non-2xx status fails the whole batch with a
+// *httpfetch.StatusError.
+func (f *Fetcher[K, V]) Fetch(keys []K) (map[K]V, error) {
+	return f.FetchContext(context.Background(), keys)
+}
+
+// FetchContext is like Fetch, but makes the request with ctx, so that it is
+// cancelled along with the batch.
+func (f *Fetcher[K, V]) FetchContext(ctx context.Context, keys []K) (map[K]V, error) {
+	body, err := json.Marshal(request[K]{Keys: keys})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, f.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := f.config.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody := io.LimitReader(resp.Body, f.config.maxBodySize)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(respBody, 512))
+		return nil, &httpfetch.StatusError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Body:       strings.TrimSpace(string(msg)),
+		}
+	}
+
+	var res response[K, V]
+	if err = json.NewDecoder(respBody).Decode(&res); err != nil {
+		return nil, err
+	}
+
+	requested := make(map[K]bool, len(keys))
+	for _, k := range keys {
+		requested[k] = true
+	}
+
+	ret := make(map[K]V, len(res.Results))
+	var keyErrs dataloader.KeyErrors[K]
+	for _, r := range res.Results {
+		switch {
+		case !requested[r.Key]:
+			// the Loader only expects results for the keys it asked for
+			continue
+		case r.Error != nil && r.Error.Code == CodeNotFound:
+			continue
+		case r.Error != nil:
+			if keyErrs == nil {
+				keyErrs = make(dataloader.KeyErrors[K])
+			}
+			keyErrs[r.Key] = r.Error
+		case r.Value != nil:
+			ret[r.Key] = *r.Value
+		}
+	}
+
+	if keyErrs != nil {
+		return ret, keyErrs
+	}
+	return ret, nil
+}
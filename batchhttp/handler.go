@@ -0,0 +1,93 @@
+package batchhttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/jsocol/dataloader"
+	"github.com/jsocol/dataloader/internal/errclass"
+)
+
+type handler[K comparable, V any] struct {
+	src    dataloader.Source[K, V]
+	config config
+}
+
+// NewHandler creates an http.Handler that loads the requested keys from src,
+// which is usually a *dataloader.Loader, so that concurrent requests are
+// batched together.
+func NewHandler[K comparable, V any](src dataloader.Source[K, V], opts ...Option) http.Handler {
+	return &handler[K, V]{
+		src:    src,
+		config: newConfig(opts),
+	}
+}
+
+func (h *handler[K, V]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req request[K]
+	body := http.MaxBytesReader(w, r.Body, h.config.maxBodySize)
+	if err := json.NewDecoder(body).Decode(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "request too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "invalid request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	_, _ = io.Copy(io.Discard, body)
+
+	if len(req.Keys) > h.config.maxKeys {
+		http.Error(w, fmt.Sprintf("too many keys: %d > %d", len(req.Keys), h.config.maxKeys), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	resp := response[K, V]{
+		Results: make([]result[K, V], len(req.Keys)),
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(len(req.Keys))
+	for i, k := range req.Keys {
+		go func(i int, k K) {
+			defer wg.Done()
+			resp.Results[i] = h.load(r.Context(), k)
+		}(i, k)
+	}
+	wg.Wait()
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func (h *handler[K, V]) load(ctx context.Context, k K) result[K, V] {
+	v, err := h.src.LoadContext(ctx, k)
+	if err == nil {
+		return result[K, V]{Key: k, Value: &v}
+	}
+
+	class := errclass.Of(err)
+	rErr := &RemoteError{
+		Code:    strings.ToLower(class.String()),
+		Message: "internal error",
+	}
+	if class.Exposed() {
+		rErr.Message = err.Error()
+	}
+	return result[K, V]{
+		Key:   k,
+		Error: rErr,
+	}
+}
@@ -0,0 +1,208 @@
+package dataloader
+
+import (
+	"sync"
+	"time"
+)
+
+// BreakerState is the state of a circuit breaker.
+type BreakerState int
+
+const (
+	// BreakerClosed sends every batch to the fetcher.
+	BreakerClosed BreakerState = iota
+	// BreakerOpen fails every batch with ErrCircuitOpen, without calling
+	// the fetcher.
+	BreakerOpen
+	// BreakerHalfOpen sends one batch to the fetcher to probe whether the
+	// upstream has recovered, and fails the rest with ErrCircuitOpen.
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// BreakerPolicy controls the circuit breaker set by WithCircuitBreaker.
+//
+// A batch fails if the fetcher returns an error other than KeyErrors, or
+// panics. Once at least MinBatches batches have been fetched in the last
+// Window, and FailureRatio of them failed, the breaker opens. After Cooldown,
+// it half-opens and lets one batch through: if that batch succeeds, the
+// breaker closes, and if not, it opens again.
+type BreakerPolicy struct {
+	// FailureRatio is the fraction of batches, between 0 and 1, that must
+	// fail to open the breaker. The default is 0.5.
+	FailureRatio float64
+
+	// MinBatches is the fewest batches in the window that can open the
+	// breaker, so that one failure on a quiet loader does not. The default
+	// is 10.
+	MinBatches int
+
+	// Window is how far back batches are counted. The default is 10s.
+	Window time.Duration
+
+	// Cooldown is how long the breaker stays open before it half-opens. The
+	// default is 5s.
+	Cooldown time.Duration
+
+	// OnStateChange, if set, is called whenever the breaker changes state,
+	// for example to log or record metrics. It must not block.
+	OnStateChange func(from, to BreakerState)
+}
+
+// breakerBuckets is the number of buckets the window is split into. Batches
+// leave the window one bucket at a time.
+const breakerBuckets = 10
+
+type breakerBucket struct {
+	start    time.Time
+	batches  int
+	failures int
+}
+
+// breaker is a circuit breaker. A nil *breaker lets every batch through.
+type breaker struct {
+	mu       sync.Mutex
+	policy   BreakerPolicy
+	state    BreakerState
+	openedAt time.Time
+	probing  bool
+	buckets  [breakerBuckets]breakerBucket
+}
+
+func newBreaker(p BreakerPolicy) *breaker {
+	if p.FailureRatio <= 0 {
+		p.FailureRatio = 0.5
+	}
+	if p.MinBatches <= 0 {
+		p.MinBatches = 10
+	}
+	if p.Window <= 0 {
+		p.Window = 10 * time.Second
+	}
+	if p.Cooldown <= 0 {
+		p.Cooldown = 5 * time.Second
+	}
+	return &breaker{policy: p}
+}
+
+// allow returns ErrCircuitOpen if a batch must not be sent to the fetcher. If
+// it returns nil, the caller must call done with the outcome of the batch.
+func (b *breaker) allow() error {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	from := b.state
+	switch b.state {
+	case BreakerOpen:
+		if time.Since(b.openedAt) < b.policy.Cooldown {
+			b.mu.Unlock()
+			return ErrCircuitOpen
+		}
+		b.state = BreakerHalfOpen
+		b.probing = true
+	case BreakerHalfOpen:
+		if b.probing {
+			b.mu.Unlock()
+			return ErrCircuitOpen
+		}
+		b.probing = true
+	}
+	to := b.state
+	b.mu.Unlock()
+
+	b.changed(from, to)
+	return nil
+}
+
+// done records whether a batch that allow let through failed.
+func (b *breaker) done(failed bool) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	from := b.state
+	now := time.Now()
+	switch b.state {
+	case BreakerHalfOpen:
+		b.probing = false
+		if failed {
+			b.state = BreakerOpen
+			b.openedAt = now
+		} else {
+			b.state = BreakerClosed
+			b.buckets = [breakerBuckets]breakerBucket{}
+		}
+	case BreakerClosed:
+		if b.record(now, failed) {
+			b.state = BreakerOpen
+			b.openedAt = now
+		}
+	}
+	// batches that were let through before the breaker opened do not change
+	// it once it is open
+	to := b.state
+	b.mu.Unlock()
+
+	b.changed(from, to)
+}
+
+// record counts a batch in the current bucket, and reports whether the
+// batches in the window have failed often enough to open the breaker.
+func (b *breaker) record(now time.Time, failed bool) bool {
+	width := b.policy.Window / breakerBuckets
+	if width <= 0 {
+		width = 1
+	}
+	start := now.Truncate(width)
+	bucket := &b.buckets[int(start.UnixNano()/int64(width))%breakerBuckets]
+	if !bucket.start.Equal(start) {
+		*bucket = breakerBucket{start: start}
+	}
+	bucket.batches++
+	if failed {
+		bucket.failures++
+	}
+
+	var batches, failures int
+	for _, bk := range b.buckets {
+		if now.Sub(bk.start) < b.policy.Window {
+			batches += bk.batches
+			failures += bk.failures
+		}
+	}
+	return batches >= b.policy.MinBatches &&
+		float64(failures) >= b.policy.FailureRatio*float64(batches)
+}
+
+// abort is called instead of done for a batch that allow let through, but
+// that was abandoned before its outcome was known.
+func (b *breaker) abort() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+func (b *breaker) changed(from, to BreakerState) {
+	if from != to && b.policy.OnStateChange != nil {
+		b.policy.OnStateChange(from, to)
+	}
+}
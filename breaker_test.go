@@ -0,0 +1,124 @@
+package dataloader_test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jsocol/dataloader"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	var calls atomic.Int64
+	var down atomic.Bool
+	errBadGateway := errors.New("502 Bad Gateway")
+	fetcher := func(keys []string) (map[string]int, error) {
+		calls.Add(1)
+		if down.Load() {
+			return nil, errBadGateway
+		}
+		ret := make(map[string]int, len(keys))
+		for _, k := range keys {
+			ret[k] = len(k)
+		}
+		return ret, nil
+	}
+
+	var mu sync.Mutex
+	var changes []string
+	l := dataloader.New(fetcher, dataloader.WithCircuitBreaker(dataloader.BreakerPolicy{
+		MinBatches: 2,
+		Cooldown:   20 * time.Millisecond,
+		OnStateChange: func(from, to dataloader.BreakerState) {
+			mu.Lock()
+			defer mu.Unlock()
+			changes = append(changes, from.String()+" -> "+to.String())
+		},
+	}))
+
+	down.Store(true)
+	_, err := l.Load("a")
+	assert.ErrorIs(t, err, errBadGateway)
+	_, err = l.Load("b")
+	assert.ErrorIs(t, err, errBadGateway)
+
+	_, err = l.Load("c")
+	assert.ErrorIs(t, err, dataloader.ErrCircuitOpen)
+	assert.Equal(t, int64(2), calls.Load())
+
+	// the probe fails, so the breaker opens again
+	time.Sleep(25 * time.Millisecond)
+	_, err = l.Load("d")
+	assert.ErrorIs(t, err, errBadGateway)
+	_, err = l.Load("e")
+	assert.ErrorIs(t, err, dataloader.ErrCircuitOpen)
+	assert.Equal(t, int64(3), calls.Load())
+
+	down.Store(false)
+	time.Sleep(25 * time.Millisecond)
+	v, err := l.Load("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, v)
+
+	v, err = l.Load("quux")
+	assert.NoError(t, err)
+	assert.Equal(t, 4, v)
+
+	assert.Equal(t, []string{
+		"closed -> open",
+		"open -> half-open",
+		"half-open -> open",
+		"open -> half-open",
+		"half-open -> closed",
+	}, changes)
+}
+
+func TestCircuitBreakerFetcherCircuitOpen(t *testing.T) {
+	var mu sync.Mutex
+	var failure error
+	fail := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		failure = err
+	}
+	fetcher := func(keys []string) (map[string]int, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if failure != nil {
+			return nil, failure
+		}
+		ret := make(map[string]int, len(keys))
+		for _, k := range keys {
+			ret[k] = len(k)
+		}
+		return ret, nil
+	}
+
+	l := dataloader.New(fetcher, dataloader.WithCircuitBreaker(dataloader.BreakerPolicy{
+		MinBatches: 1,
+		Cooldown:   20 * time.Millisecond,
+	}))
+
+	fail(errors.New("502 Bad Gateway"))
+	_, err := l.Load("a")
+	assert.Error(t, err)
+
+	// the probe fails with the breaker of another loader, which still
+	// counts as a failure of this one's upstream
+	fail(fmt.Errorf("upstream: %w", dataloader.ErrCircuitOpen))
+	time.Sleep(25 * time.Millisecond)
+	_, err = l.Load("b")
+	assert.ErrorIs(t, err, dataloader.ErrCircuitOpen)
+
+	// so the breaker probes again once the upstream recovers
+	fail(nil)
+	time.Sleep(25 * time.Millisecond)
+	v, err := l.Load("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, v)
+}
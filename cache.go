@@ -0,0 +1,113 @@
+package dataloader
+
+import (
+	"context"
+	"time"
+)
+
+// Entry is a value with a time to live, as returned by a CacheFetcher.
+type Entry[V any] struct {
+	Value V
+
+	// TTL is how long the Loader may cache the value. Zero means the default
+	// set by WithCache, and a negative TTL means the value must not be cached.
+	TTL time.Duration
+}
+
+// CacheFetcher is like Fetcher, but sets how long each value may be cached, for
+// example from the Cache-Control header of an HTTP response.
+type CacheFetcher[K comparable, V any] func([]K) (map[K]Entry[V], error)
+
+// NewCache creates a Loader that caches each value for as long as the fetcher
+// says it may. Use WithCache to set a TTL for entries that do not have one.
+func NewCache[K comparable, V any](fetchFn CacheFetcher[K, V], opts ...Option) *Loader[K, V] {
+	return newLoader(func(_ context.Context, keys []K) (map[K]Entry[V], error) {
+		return fetchFn(keys)
+	}, append([]Option{WithCache(0)}, opts...)...)
+}
+
+// ContextCacheFetcher is like CacheFetcher, for a fetcher that accepts a
+// context, as with ContextFetcher.
+type ContextCacheFetcher[K comparable, V any] func(context.Context, []K) (map[K]Entry[V], error)
+
+// NewCacheContext is like NewCache, for a fetcher that accepts a context.
+func NewCacheContext[K comparable, V any](fetchFn ContextCacheFetcher[K, V], opts ...Option) *Loader[K, V] {
+	return newLoader(fetchFn, append([]Option{WithCache(0)}, opts...)...)
+}
+
+// Forget removes key from the cache, so the next Load fetches it again.
+func (l *Loader[K, V]) Forget(key K) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.cache != nil {
+		delete(l.cache.entries, l.keyFn(key))
+	}
+}
+
+type cacheEntry[V any] struct {
+	value   V
+	expires time.Time
+}
+
+// cache holds fetched values until they expire. Expired entries are removed
+// when they are next looked up, or by a sweep once the cache has doubled in
+// size since the last one. It is guarded by the Loader's mutex.
+type cache[K comparable, V any] struct {
+	entries   map[K]cacheEntry[V]
+	ttl       time.Duration
+	nextSweep int
+}
+
+const minSweep = 64
+
+func newCache[K comparable, V any](ttl time.Duration) *cache[K, V] {
+	return &cache[K, V]{
+		entries:   make(map[K]cacheEntry[V]),
+		ttl:       ttl,
+		nextSweep: minSweep,
+	}
+}
+
+func (c *cache[K, V]) get(k K) (V, bool) {
+	e, ok := c.entries[k]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	if time.Now().After(e.expires) {
+		delete(c.entries, k)
+		var zero V
+		return zero, false
+	}
+	return e.value, true
+}
+
+func (c *cache[K, V]) set(k K, e Entry[V]) {
+	ttl := e.TTL
+	if ttl == 0 {
+		ttl = c.ttl
+	}
+	if ttl <= 0 {
+		return
+	}
+
+	c.entries[k] = cacheEntry[V]{
+		value:   e.Value,
+		expires: time.Now().Add(ttl),
+	}
+
+	if len(c.entries) >= c.nextSweep {
+		c.sweep()
+	}
+}
+
+func (c *cache[K, V]) sweep() {
+	now := time.Now()
+	for k, e := range c.entries {
+		if now.After(e.expires) {
+			delete(c.entries, k)
+		}
+	}
+	c.nextSweep = max(2*len(c.entries), minSweep)
+}
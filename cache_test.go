@@ -0,0 +1,101 @@
+package dataloader_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jsocol/dataloader"
+)
+
+func TestWithCache(t *testing.T) {
+	var calls atomic.Int64
+	fetcher := func(keys []string) (map[string]int, error) {
+		calls.Add(1)
+		ret := make(map[string]int, len(keys))
+		for _, k := range keys {
+			ret[k] = len(k)
+		}
+		return ret, nil
+	}
+
+	l := dataloader.New(fetcher, dataloader.WithCache(time.Hour))
+
+	v, err := l.Load("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, v)
+
+	v, err = l.Load("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, v)
+	assert.Equal(t, int64(1), calls.Load())
+
+	l.Forget("foo")
+
+	v, err = l.Load("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, v)
+	assert.Equal(t, int64(2), calls.Load())
+}
+
+func TestNewCache(t *testing.T) {
+	var calls atomic.Int64
+	fetcher := func(keys []string) (map[string]dataloader.Entry[string], error) {
+		calls.Add(1)
+		ret := make(map[string]dataloader.Entry[string], len(keys))
+		for _, k := range keys {
+			switch k {
+			case "short":
+				ret[k] = dataloader.Entry[string]{Value: k, TTL: 10 * time.Millisecond}
+			case "no-store":
+				ret[k] = dataloader.Entry[string]{Value: k, TTL: -1}
+			default:
+				ret[k] = dataloader.Entry[string]{Value: k}
+			}
+		}
+		return ret, nil
+	}
+
+	l := dataloader.NewCache(fetcher, dataloader.WithCache(time.Hour))
+
+	for _, k := range []string{"short", "no-store", "default"} {
+		v, err := l.Load(k)
+		assert.NoError(t, err)
+		assert.Equal(t, k, v)
+	}
+	assert.Equal(t, int64(3), calls.Load())
+
+	// only the default entry is still cached after the short one expires
+	time.Sleep(20 * time.Millisecond)
+	for _, k := range []string{"short", "no-store", "default"} {
+		v, err := l.Load(k)
+		assert.NoError(t, err)
+		assert.Equal(t, k, v)
+	}
+	assert.Equal(t, int64(5), calls.Load())
+}
+
+func TestNewCacheContext(t *testing.T) {
+	var calls atomic.Int64
+	fetcher := func(ctx context.Context, keys []string) (map[string]dataloader.Entry[string], error) {
+		calls.Add(1)
+		assert.NoError(t, ctx.Err())
+		ret := make(map[string]dataloader.Entry[string], len(keys))
+		for _, k := range keys {
+			ret[k] = dataloader.Entry[string]{Value: k, TTL: time.Hour}
+		}
+		return ret, nil
+	}
+
+	l := dataloader.NewCacheContext(fetcher)
+
+	for range 2 {
+		v, err := l.Load("foo")
+		assert.NoError(t, err)
+		assert.Equal(t, "foo", v)
+	}
+	assert.Equal(t, int64(1), calls.Load())
+}
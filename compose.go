@@ -0,0 +1,100 @@
+package dataloader
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Source is anything that can load a value by key, like a *Loader, or the
+// loaders built from other loaders by Map and Then.
+type Source[K comparable, V any] interface {
+	Load(K) (V, error)
+	LoadContext(context.Context, K) (V, error)
+}
+
+// Derived is a loader built from other loaders by Map, Then or ThenMany. It
+// does no batching of its own: each Load goes through the loaders it was built
+// from, which batch as usual. The context given to LoadContext is passed to
+// each of them.
+type Derived[K comparable, V any] struct {
+	load func(context.Context, K) (V, error)
+}
+
+// Map creates a loader that transforms each value loaded from src with fn.
+func Map[K comparable, V, W any](src Source[K, V], fn func(V) W) *Derived[K, W] {
+	return &Derived[K, W]{
+		load: func(ctx context.Context, key K) (W, error) {
+			v, err := src.LoadContext(ctx, key)
+			if err != nil {
+				var zero W
+				return zero, err
+			}
+			return fn(v), nil
+		},
+	}
+}
+
+// Then creates a loader that loads a value from src, uses fn to derive a key
+// for next, and loads that key from next. Concurrent calls are batched at both
+// steps, so N loads cost one fetch from each loader.
+func Then[K, J comparable, V, W any](src Source[K, V], fn func(V) J, next Source[J, W]) *Derived[K, W] {
+	return &Derived[K, W]{
+		load: func(ctx context.Context, key K) (W, error) {
+			v, err := src.LoadContext(ctx, key)
+			if err != nil {
+				var zero W
+				return zero, err
+			}
+			return next.LoadContext(ctx, fn(v))
+		},
+	}
+}
+
+// ThenMany is like Then, for values that refer to several keys of next, like a
+// book and its authors. The keys are loaded concurrently, so they are batched
+// together, and the values are returned in the same order as the keys fn
+// returns. If any key fails to load, ThenMany returns all of the errors.
+func ThenMany[K, J comparable, V, W any](src Source[K, V], fn func(V) []J, next Source[J, W]) *Derived[K, []W] {
+	return &Derived[K, []W]{
+		load: func(ctx context.Context, key K) ([]W, error) {
+			v, err := src.LoadContext(ctx, key)
+			if err != nil {
+				return nil, err
+			}
+
+			keys := fn(v)
+			ret := make([]W, len(keys))
+			errs := make([]error, len(keys))
+
+			var wg sync.WaitGroup
+			wg.Add(len(keys))
+			for i, k := range keys {
+				go func(i int, k J) {
+					defer wg.Done()
+					ret[i], errs[i] = next.LoadContext(ctx, k)
+				}(i, k)
+			}
+			wg.Wait()
+
+			if err := errors.Join(errs...); err != nil {
+				return nil, err
+			}
+			return ret, nil
+		},
+	}
+}
+
+func (d *Derived[K, V]) Load(key K) (V, error) {
+	return d.LoadContext(context.Background(), key)
+}
+
+// LoadContext is like Load, but passes ctx to the loaders d was built from, so
+// that it stops waiting when ctx is done.
+func (d *Derived[K, V]) LoadContext(ctx context.Context, key K) (V, error) {
+	return d.load(ctx, key)
+}
+
+func (d *Derived[K, V]) LoadMany(keys ...K) ([]V, []error) {
+	return loadMany(keys, d.Load)
+}
@@ -0,0 +1,126 @@
+package dataloader_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jsocol/dataloader"
+)
+
+type authoredBook struct {
+	ID      string
+	Authors []string
+}
+
+func TestMap(t *testing.T) {
+	fetcher := func(keys []string) (map[string]string, error) {
+		ret := make(map[string]string, len(keys))
+		for _, k := range keys {
+			if k == "foo" {
+				ret[k] = "yes-foo"
+			}
+		}
+		return ret, nil
+	}
+
+	l := dataloader.Map(dataloader.New(fetcher), strings.ToUpper)
+
+	v, err := l.Load("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, "YES-FOO", v)
+
+	_, err = l.Load("bar")
+	assert.ErrorIs(t, err, dataloader.ErrNotFound)
+}
+
+func TestThen(t *testing.T) {
+	var bookCalls, personCalls atomic.Int64
+	bookData := map[string]authoredBook{
+		"urn:isbn:978-1098149482": {ID: "urn:isbn:978-1098149482", Authors: []string{"will-larson"}},
+		"urn:isbn:978-1098118730": {ID: "urn:isbn:978-1098118730", Authors: []string{"tanya-reilly"}},
+		"urn:isbn:978-1736417911": {ID: "urn:isbn:978-1736417911", Authors: []string{"will-larson", "tanya-reilly"}},
+	}
+	books := dataloader.New(func(ids []string) (map[string]authoredBook, error) {
+		bookCalls.Add(1)
+		ret := make(map[string]authoredBook, len(ids))
+		for _, id := range ids {
+			ret[id] = bookData[id]
+		}
+		return ret, nil
+	})
+	people := dataloader.New(func(ids []string) (map[string]string, error) {
+		personCalls.Add(1)
+		assert.ElementsMatch(t, []string{"will-larson", "tanya-reilly"}, ids)
+		names := map[string]string{
+			"will-larson":  "Will Larson",
+			"tanya-reilly": "Tanya Reilly",
+		}
+		ret := make(map[string]string, len(ids))
+		for _, id := range ids {
+			ret[id] = names[id]
+		}
+		return ret, nil
+	})
+
+	firstAuthor := dataloader.Then(books, func(b authoredBook) string { return b.Authors[0] }, people)
+	allAuthors := dataloader.ThenMany(books, func(b authoredBook) []string { return b.Authors }, people)
+
+	var wg sync.WaitGroup
+	wg.Add(3)
+
+	go func() {
+		defer wg.Done()
+		v, err := firstAuthor.Load("urn:isbn:978-1098149482")
+		assert.NoError(t, err)
+		assert.Equal(t, "Will Larson", v)
+	}()
+
+	go func() {
+		defer wg.Done()
+		v, err := firstAuthor.Load("urn:isbn:978-1098118730")
+		assert.NoError(t, err)
+		assert.Equal(t, "Tanya Reilly", v)
+	}()
+
+	go func() {
+		defer wg.Done()
+		vs, err := allAuthors.Load("urn:isbn:978-1736417911")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"Will Larson", "Tanya Reilly"}, vs)
+	}()
+
+	wg.Wait()
+
+	assert.Equal(t, int64(1), bookCalls.Load())
+	assert.Equal(t, int64(1), personCalls.Load())
+}
+
+func TestThenContext(t *testing.T) {
+	books := dataloader.New(func(ids []string) (map[string]authoredBook, error) {
+		ret := make(map[string]authoredBook, len(ids))
+		for _, id := range ids {
+			ret[id] = authoredBook{ID: id, Authors: []string{"tanya-reilly"}}
+		}
+		return ret, nil
+	})
+	// the people fetcher only returns once the batch is abandoned, so the
+	// load can only end with the caller's context
+	people := dataloader.NewContext(func(ctx context.Context, ids []string) (map[string]string, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	authors := dataloader.ThenMany(books, func(b authoredBook) []string { return b.Authors }, people)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := authors.LoadContext(ctx, "urn:isbn:978-1098118730")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
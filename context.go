@@ -0,0 +1,15 @@
+package dataloader
+
+import "context"
+
+// ContextFetcher is like Fetcher, but takes a context that is cancelled when
+// the batch is abandoned, for example because of WithFetchTimeout. The
+// context belongs to the batch, not to any one caller.
+type ContextFetcher[K comparable, V any] func(context.Context, []K) (map[K]V, error)
+
+// NewContext creates a Loader for a fetcher that accepts a context.
+func NewContext[K comparable, V any](fetchFn ContextFetcher[K, V], opts ...Option) *Loader[K, V] {
+	return newLoader(func(ctx context.Context, keys []K) (map[K]Entry[V], error) {
+		return entries(fetchFn(ctx, keys))
+	}, opts...)
+}
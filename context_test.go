@@ -0,0 +1,214 @@
+package dataloader_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jsocol/dataloader"
+)
+
+func TestFetchTimeout(t *testing.T) {
+	cancelled := make(chan struct{})
+	fetcher := func(ctx context.Context, keys []string) (map[string]int, error) {
+		<-ctx.Done()
+		close(cancelled)
+		return nil, ctx.Err()
+	}
+
+	l := dataloader.NewContext(fetcher, dataloader.WithFetchTimeout(10*time.Millisecond))
+
+	_, err := l.Load("foo")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	var timeoutErr *dataloader.TimeoutError
+	if assert.ErrorAs(t, err, &timeoutErr) {
+		assert.Equal(t, "fetch", timeoutErr.Op)
+		assert.True(t, timeoutErr.Timeout())
+	}
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Error("fetcher context was not cancelled")
+	}
+}
+
+func TestLoadTimeout(t *testing.T) {
+	release := make(chan struct{})
+	fetcher := func(keys []string) (map[string]int, error) {
+		ret := make(map[string]int, len(keys))
+		for _, k := range keys {
+			if k == "stuck" {
+				<-release
+			}
+			ret[k] = len(k)
+		}
+		return ret, nil
+	}
+
+	l := dataloader.New(fetcher, dataloader.WithLoadTimeout(20*time.Millisecond))
+
+	start := time.Now()
+	_, err := l.Load("stuck")
+	assert.Less(t, time.Since(start), time.Second)
+
+	var timeoutErr *dataloader.TimeoutError
+	if assert.ErrorAs(t, err, &timeoutErr) {
+		assert.Equal(t, "load", timeoutErr.Op)
+		assert.Equal(t, 20*time.Millisecond, timeoutErr.After)
+	}
+
+	// the stuck batch does not block other keys
+	v, err := l.Load("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, v)
+
+	// the late result is dropped
+	close(release)
+	time.Sleep(5 * time.Millisecond)
+}
+
+func TestLoadContextDropsKey(t *testing.T) {
+	rec := &recorder{}
+	l := dataloader.New(rec.fetch, dataloader.WithDelay(30*time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(5*time.Millisecond, cancel)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		_, err := l.LoadContext(ctx, "gone")
+		assert.ErrorIs(t, err, context.Canceled)
+	}()
+	go func() {
+		defer wg.Done()
+		v, err := l.Load("kept")
+		assert.NoError(t, err)
+		assert.Equal(t, 4, v)
+	}()
+	wg.Wait()
+
+	assert.Equal(t, [][]string{{"kept"}}, rec.Batches())
+}
+
+func TestLoadContextCancelsFetch(t *testing.T) {
+	started := make(chan struct{})
+	cancelled := make(chan struct{})
+	fetcher := func(ctx context.Context, keys []string) (map[string]int, error) {
+		close(started)
+		<-ctx.Done()
+		close(cancelled)
+		return nil, ctx.Err()
+	}
+
+	l := dataloader.NewContext(fetcher)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+
+	_, err := l.LoadContext(ctx, "foo")
+	assert.ErrorIs(t, err, context.Canceled)
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Error("fetcher context was not cancelled")
+	}
+}
+
+func TestDeadlineDispatch(t *testing.T) {
+	var mu sync.Mutex
+	var fetched time.Time
+	fetcher := func(keys []string) (map[string]int, error) {
+		mu.Lock()
+		fetched = time.Now()
+		mu.Unlock()
+
+		ret := make(map[string]int, len(keys))
+		for _, k := range keys {
+			if k == "slow" {
+				time.Sleep(40 * time.Millisecond)
+			}
+			ret[k] = len(k)
+		}
+		return ret, nil
+	}
+
+	l := dataloader.New(fetcher, dataloader.WithDelay(200*time.Millisecond))
+
+	// teaches the loader that fetches take about 40ms
+	_, err := l.Load("slow")
+	assert.NoError(t, err)
+
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Millisecond)
+	defer cancel()
+
+	v, err := l.LoadContext(ctx, "foo")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, v)
+
+	// the batch is fetched about 40ms before the deadline, not after the
+	// 200ms delay
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Greater(t, fetched.Sub(start), 50*time.Millisecond)
+	assert.Less(t, fetched.Sub(start), 110*time.Millisecond)
+}
+
+func TestDeadlineDispatchColdStart(t *testing.T) {
+	var calls atomic.Int64
+	fetcher := func(keys []string) (map[string]int, error) {
+		calls.Add(1)
+		return nil, nil
+	}
+
+	l := dataloader.New(fetcher, dataloader.WithDelay(50*time.Millisecond))
+
+	// with no fetch to go by, the batch is not raced against the deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := l.LoadContext(ctx, "foo")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	time.Sleep(60 * time.Millisecond)
+	assert.Zero(t, calls.Load())
+}
+
+func TestLoadContextCancelledAlone(t *testing.T) {
+	fetcher := func(keys []string) (map[string]int, error) {
+		ret := make(map[string]int, len(keys))
+		for _, k := range keys {
+			ret[k] = len(k)
+		}
+		return ret, nil
+	}
+
+	l := dataloader.New(fetcher, dataloader.WithDelay(50*time.Millisecond))
+
+	// a cancel, unlike a deadline, gives the loader no reason to fetch early
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(2*time.Millisecond, cancel)
+	_, err := l.LoadContext(ctx, "gone")
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// once the cancelled load's batch would have been due, the loader still
+	// starts new batches
+	time.Sleep(60 * time.Millisecond)
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	v, err := l.LoadContext(ctx, "x")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, v)
+}
@@ -1,182 +1,809 @@
 package dataloader
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"runtime/debug"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
-type Error[K comparable] interface {
-	error
-	Key() K
-}
-
-type keyError[K comparable] struct {
-	msg string
-	key K
+type result[T any] struct {
+	value T
+	err   error
 }
 
-func (k *keyError[K]) Error() string {
-	return fmt.Sprintf("%s (%v)", k.msg, k.key)
+// waiter is a single caller waiting on a key. The key is the one the caller
+// asked for, before any normalization. Once the key is taken into a batch,
+// batch is set, guarded by the Loader's mutex.
+type waiter[K comparable, V any] struct {
+	key   K
+	ch    chan *result[V]
+	batch *batch[K, V]
 }
 
-func (k *keyError[K]) Key() K {
-	return k.key
+// batch is a set of tasks taken from the Loader to be fetched together. Its
+// context is cancelled once it has no waiters left, either because they have
+// their results or because they have gone away.
+type batch[K comparable, V any] struct {
+	tasks  map[K][]*waiter[K, V]
+	ctx    context.Context
+	cancel context.CancelFunc
+	live   atomic.Int64
 }
 
-type result[T any] struct {
-	value T
-	err   error
+// leave is called when a waiter in the batch stops waiting.
+func (b *batch[K, V]) leave() {
+	if b.live.Add(-1) == 0 {
+		b.cancel()
+	}
 }
 
 // The Fetcher function should take a list of keys and return a map of keys to
 // values. This may involve network requests or other slow or expensive calls.
+//
+// To fail some keys without failing the whole batch, return the values that
+// were found along with a KeyErrors.
 type Fetcher[K comparable, V any] func([]K) (map[K]V, error)
 
+// KeyErrors is returned by a fetcher, along with the values it did find, when
+// some keys failed but others succeeded. Callers waiting on a key in the map
+// get its error, wrapped in an Error[K]. Keys that are neither in the results
+// nor in KeyErrors are not found.
+type KeyErrors[K comparable] map[K]error
+
+func (e KeyErrors[K]) Error() string {
+	if len(e) == 1 {
+		for k, err := range e {
+			return fmt.Sprintf("%s (%v)", err, k)
+		}
+	}
+	return fmt.Sprintf("errors loading %d keys", len(e))
+}
+
 type config struct {
 	delay    time.Duration
 	maxBatch int
+	keyFunc  any
+	costFunc any
+	fallback any
+	maxCost  int
+	cache    bool
+	ttl      time.Duration
+	retry    *RetryPolicy
+	breaker  *BreakerPolicy
+	rate     *RateLimit
+	hedge    *HedgePolicy
+
+	fetchTimeout time.Duration
+	loadTimeout  time.Duration
+
+	highDelay time.Duration
+	lowDelay  time.Duration
+
+	maxPending int
+	overflow   OverflowPolicy
 }
 
 // Loader is a generic implementation of the GraphQL "data loader" pattern that
 // collapses several individual lookups by a key into one lookup as a list.
 type Loader[K comparable, V any] struct {
-	mu      sync.Mutex
-	tasks   map[K][]chan *result[V]
-	fetcher Fetcher[K, V]
-	tick    <-chan time.Time
-	config  config
+	mu       sync.Mutex
+	tasks    map[K][]*waiter[K, V]
+	low      map[K][]*waiter[K, V]
+	fetcher  func(context.Context, []K) (map[K]Entry[V], error)
+	fallback func(context.Context, []K) (map[K]Entry[V], error)
+	keyFn    func(K) K
+	costFn   func(K) int
+	cost     int
+	timer    *time.Timer
+	due      time.Time
+	lowTimer *time.Timer
+	lowDue   time.Time
+	latency  latency
+	cache    *cache[K, V]
+	breaker  *breaker
+	limiter  *limiter
+	pending  chan struct{}
+	config   config
 }
 
 func New[K comparable, V any](fetchFn Fetcher[K, V], opts ...Option) *Loader[K, V] {
+	return newLoader(func(_ context.Context, keys []K) (map[K]Entry[V], error) {
+		return entries(fetchFn(keys))
+	}, opts...)
+}
+
+// entries wraps the values returned by a fetcher in entries with no TTL.
+func entries[K comparable, V any](values map[K]V, err error) (map[K]Entry[V], error) {
+	ret := make(map[K]Entry[V], len(values))
+	for k, v := range values {
+		ret[k] = Entry[V]{Value: v}
+	}
+	return ret, err
+}
+
+func newLoader[K comparable, V any](fetchFn func(context.Context, []K) (map[K]Entry[V], error), opts ...Option) *Loader[K, V] {
 	c := config{
 		delay: time.Millisecond,
 	}
 	for _, o := range opts {
 		o(&c)
 	}
-	return &Loader[K, V]{
-		fetcher: fetchFn,
-		tasks:   make(map[K][]chan *result[V]),
-		config:  c,
+	if c.lowDelay <= 0 {
+		c.lowDelay = 10 * c.delay
 	}
+
+	keyFn := func(k K) K { return k }
+	if c.keyFunc != nil {
+		fn, ok := c.keyFunc.(func(K) K)
+		if !ok {
+			var k K
+			panic(fmt.Sprintf("dataloader: WithKeyFunc must be given a func(%T) %T", k, k))
+		}
+		keyFn = fn
+	}
+
+	costFn := func(K) int { return 1 }
+	if c.costFunc != nil {
+		fn, ok := c.costFunc.(func(K) int)
+		if !ok {
+			var k K
+			panic(fmt.Sprintf("dataloader: WithCostFunc must be given a func(%T) int", k))
+		}
+		costFn = fn
+	}
+
+	var fallback func(context.Context, []K) (map[K]Entry[V], error)
+	switch fn := c.fallback.(type) {
+	case nil:
+	case Fetcher[K, V]:
+		fallback = func(_ context.Context, keys []K) (map[K]Entry[V], error) {
+			return entries(fn(keys))
+		}
+	case ContextFetcher[K, V]:
+		fallback = func(ctx context.Context, keys []K) (map[K]Entry[V], error) {
+			return entries(fn(ctx, keys))
+		}
+	default:
+		var k K
+		var v V
+		panic(fmt.Sprintf("dataloader: WithFallback must be given a Fetcher[%T, %T] or ContextFetcher[%[1]T, %[2]T]", k, v))
+	}
+
+	l := &Loader[K, V]{
+		fetcher:  fetchFn,
+		fallback: fallback,
+		tasks:    make(map[K][]*waiter[K, V]),
+		low:      make(map[K][]*waiter[K, V]),
+		keyFn:    keyFn,
+		costFn:   costFn,
+		config:   c,
+	}
+	if c.cache {
+		l.cache = newCache[K, V](c.ttl)
+	}
+	if c.breaker != nil {
+		l.breaker = newBreaker(*c.breaker)
+	}
+	if c.rate != nil {
+		l.limiter = newLimiter(*c.rate)
+	}
+	if c.maxPending > 0 {
+		l.pending = make(chan struct{}, c.maxPending)
+	}
+	return l
 }
 
 func (l *Loader[K, V]) Load(key K) (V, error) {
-	ch := make(chan *result[V], 1)
-	defer close(ch)
+	return l.LoadContext(context.Background(), key)
+}
 
-	l.enqueue(key, ch)
-	res := <-ch
+// LoadContext is like Load, but stops waiting when ctx is done, and returns
+// its error. If every caller waiting on a key goes away before its batch is
+// fetched, the key is left out of the batch, and if every caller waiting on a
+// batch goes away while it is being fetched, the fetcher's context is
+// cancelled.
+//
+// The load has the priority set on ctx by WithPriority, if any.
+func (l *Loader[K, V]) LoadContext(ctx context.Context, key K) (V, error) {
+	res := l.load(ctx, key)
 	return res.value, res.err
 }
 
 func (l *Loader[K, V]) LoadMany(keys ...K) ([]V, []error) {
+	return loadMany(keys, l.Load)
+}
+
+// loadMany calls load for each of keys concurrently, so that they are batched
+// together. It returns the values that loaded and the errors of the keys that
+// did not, in no particular order.
+func loadMany[K, V any](keys []K, load func(K) (V, error)) ([]V, []error) {
 	ret := make([]V, 0, len(keys))
 	var errs []error
 	var mu sync.Mutex
 
 	var wg sync.WaitGroup
 	wg.Add(len(keys))
-	for i, k := range keys {
-		go func(i int, k K) {
+	for _, k := range keys {
+		go func(k K) {
 			defer wg.Done()
-			ch := make(chan *result[V], 1)
-			defer close(ch)
-
-			l.enqueue(k, ch)
-			res := <-ch
+			v, err := load(k)
 
 			mu.Lock()
 			defer mu.Unlock()
 
-			if res.err != nil {
-				errs = append(errs, res.err)
+			if err != nil {
+				errs = append(errs, err)
 				return
 			}
-			ret = append(ret, res.value)
-		}(i, k)
+			ret = append(ret, v)
+		}(k)
 	}
 	wg.Wait()
 	return ret, errs
 }
 
-func (l *Loader[K, V]) enqueue(k K, ch chan *result[V]) {
+// load enqueues key and waits for its result, until ctx is done or for at most
+// the timeout set by WithLoadTimeout, including any wait for WithMaxPending.
+// The result channel is never closed, because a fetch may still send to it
+// after the load has stopped waiting.
+func (l *Loader[K, V]) load(ctx context.Context, key K) *result[V] {
+	var timeout <-chan time.Time
+	if l.config.loadTimeout > 0 {
+		timer := time.NewTimer(l.config.loadTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	if l.pending != nil {
+		select {
+		case l.pending <- struct{}{}:
+		default:
+			if l.config.overflow == FailFast {
+				return &result[V]{err: ErrOverloaded}
+			}
+			select {
+			case l.pending <- struct{}{}:
+			case <-ctx.Done():
+				return &result[V]{err: ctx.Err()}
+			case <-timeout:
+				return &result[V]{
+					err: &TimeoutError{Op: "load", After: l.config.loadTimeout},
+				}
+			}
+		}
+		defer func() { <-l.pending }()
+	}
+
+	deadline, _ := ctx.Deadline()
+	if l.config.loadTimeout > 0 {
+		if d := time.Now().Add(l.config.loadTimeout); deadline.IsZero() || d.Before(deadline) {
+			deadline = d
+		}
+	}
+
+	ch := make(chan *result[V], 1)
+	w := l.enqueue(key, ch, deadline, priorityOf(ctx))
+	select {
+	case res := <-ch:
+		if w != nil {
+			w.batch.leave()
+		}
+		return res
+	case <-ctx.Done():
+		l.abandon(w)
+		return &result[V]{err: ctx.Err()}
+	case <-timeout:
+		l.abandon(w)
+		return &result[V]{
+			err: &TimeoutError{Op: "load", After: l.config.loadTimeout},
+		}
+	}
+}
+
+// abandon removes a waiter that has stopped waiting. If its key has not been
+// taken into a batch yet, and no one else is waiting on it, the key is not
+// fetched.
+func (l *Loader[K, V]) abandon(w *waiter[K, V]) {
+	if w == nil {
+		return
+	}
+
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	l.tasks[k] = append(l.tasks[k], ch)
 
-	if l.config.maxBatch > 0 && len(l.tasks) >= l.config.maxBatch {
-		// if we've hit the max batch size, initiate a fetch immediately
-		go func() {
-			l.fetch()
-		}()
-	} else if l.tick == nil {
-		// if we aren't waiting yet, start waiting
-		l.tick = time.After(l.config.delay)
-		go func() {
-			<-l.tick
-			l.fetch()
-		}()
+	if w.batch != nil {
+		w.batch.leave()
+		return
+	}
+
+	nk := l.keyFn(w.key)
+	if removeWaiter(l.tasks, nk, w) {
+		if l.config.maxCost > 0 {
+			l.cost -= l.costFn(nk)
+		}
+		if len(l.tasks) == 0 {
+			// no one is left to fetch for, so don't
+			l.stopTimer()
+		}
+	}
+	if removeWaiter(l.low, nk, w) {
+		l.scheduleLow()
 	}
 }
 
-func (l *Loader[K, V]) fetch() {
+// removeWaiter removes w from the waiters on k in tasks, if it is there, and
+// reports whether that left no one waiting on k.
+func removeWaiter[K comparable, V any](tasks map[K][]*waiter[K, V], k K, w *waiter[K, V]) bool {
+	waiters := tasks[k]
+	i := slices.Index(waiters, w)
+	if i < 0 {
+		return false
+	}
+	if len(waiters) > 1 {
+		tasks[k] = slices.Delete(waiters, i, i+1)
+		return false
+	}
+	delete(tasks, k)
+	return true
+}
+
+// enqueue adds a waiter for k to the pending tasks, and returns it. If k is
+// cached, the result is sent to ch right away, and there is no waiter.
+//
+// If deadline is not zero, the batch is fetched early enough to be done by the
+// deadline, if the fetcher takes as long as it has been taking. Before the
+// first fetch, there is no telling, so the batch waits as usual.
+func (l *Loader[K, V]) enqueue(k K, ch chan *result[V], deadline time.Time, prio Priority) *waiter[K, V] {
 	l.mu.Lock()
 	defer l.mu.Unlock()
+	nk := l.keyFn(k)
 
-	keys := make([]K, 0, len(l.tasks))
-	for k := range l.tasks {
+	if l.cache != nil {
+		if v, ok := l.cache.get(nk); ok {
+			ch <- &result[V]{value: v}
+			return nil
+		}
+	}
+
+	w := &waiter[K, V]{key: k, ch: ch}
+	waiters, pending := l.tasks[nk]
+	if !pending {
+		low, isLow := l.low[nk]
+		if prio == PriorityLow {
+			l.low[nk] = append(low, w)
+			l.scheduleLow()
+			return w
+		}
+		if isLow {
+			// a key that's wanted sooner joins the normal batch
+			delete(l.low, nk)
+			l.scheduleLow()
+			waiters = low
+		}
+	}
+
+	if !pending && l.config.maxCost > 0 {
+		cost := l.costFn(nk)
+		if len(l.tasks) > 0 && l.cost+cost > l.config.maxCost {
+			// the key doesn't fit in the batch, so send the batch without it
+			b := l.takeTasks(len(l.tasks))
+			go func() {
+				l.limiter.wait(len(b.tasks))
+				l.fetchBatch(b)
+			}()
+		}
+		l.cost += cost
+	}
+	l.tasks[nk] = append(waiters, w)
+
+	if l.config.maxBatch > 0 && len(l.tasks) >= l.config.maxBatch ||
+		l.config.maxCost > 0 && l.cost >= l.config.maxCost {
+		// if we've hit the max batch size or cost, initiate a fetch
+		// immediately
+		go l.fetch()
+	} else {
+		due := time.Now().Add(l.config.delay)
+		if prio == PriorityHigh {
+			due = time.Now().Add(l.config.highDelay)
+		}
+		// until a fetch has been timed, fetching early would only race the
+		// caller's own deadline
+		if est, ok := l.latency.estimate(); ok && !deadline.IsZero() {
+			if latest := deadline.Add(-est); latest.Before(due) {
+				due = latest
+			}
+		}
+
+		if l.timer == nil {
+			// if we aren't waiting yet, start waiting
+			l.due = due
+			l.timer = time.AfterFunc(time.Until(due), l.fetch)
+		} else if due.Before(l.due) && l.timer.Stop() {
+			// if the waiter can't wait for the batch, fetch it sooner
+			l.due = due
+			l.timer = time.AfterFunc(time.Until(due), l.fetch)
+		}
+	}
+	return w
+}
+
+// fetch takes the pending tasks as a batch and fetches them. The lock is only
+// held while taking the batch, so callers can start the next batch while this
+// one is being fetched or retried.
+func (l *Loader[K, V]) fetch() {
+	var b *batch[K, V]
+	for b == nil {
+		l.mu.Lock()
+		n := len(l.tasks) + len(l.low)
+		if n == 0 {
+			l.stopTimer()
+			l.mu.Unlock()
+			return
+		}
+		if l.config.maxBatch > 0 {
+			n = min(n, l.config.maxBatch)
+		}
+		// while waiting for the rate limit, more keys can join the batch
+		n, wait := l.limiter.reserve(n, false)
+		if wait == 0 {
+			b = l.takeTasks(n)
+		}
+		l.mu.Unlock()
+		time.Sleep(wait)
+	}
+	l.fetchBatch(b)
+}
+
+// fetchBatch fetches the keys of b, and retries those that fail. It stops
+// if every waiter on the batch goes away.
+func (l *Loader[K, V]) fetchBatch(b *batch[K, V]) {
+	defer b.cancel()
+
+	keys := make([]K, 0, len(b.tasks))
+	for k := range b.tasks {
 		keys = append(keys, k)
 	}
 
-	results, err := l.fetcher(keys)
-	if err != nil {
-		l.sendError(err)
-		return
+	for attempt := 1; len(keys) > 0 && b.ctx.Err() == nil; attempt++ {
+		if attempt > 1 {
+			time.Sleep(l.config.retry.backoff(attempt - 1))
+			l.limiter.wait(len(keys))
+		}
+		keys = l.fetchAttempt(b, keys, attempt)
+	}
+}
+
+// takeTasks removes up to n pending tasks and returns them as a batch. If any
+// are left, they are fetched in another batch. Low priority tasks are only
+// taken if there is room for them. It must be called with the lock held.
+func (l *Loader[K, V]) takeTasks(n int) *batch[K, V] {
+	var tasks map[K][]*waiter[K, V]
+	cost := l.cost
+	if n >= len(l.tasks) {
+		tasks = l.tasks
+		l.tasks = make(map[K][]*waiter[K, V])
+		l.cost = 0
+		l.stopTimer()
+	} else {
+		tasks = make(map[K][]*waiter[K, V], n)
+		for k, waiters := range l.tasks {
+			if len(tasks) == n {
+				break
+			}
+			tasks[k] = waiters
+			delete(l.tasks, k)
+			if l.config.maxCost > 0 {
+				l.cost -= l.costFn(k)
+			}
+		}
+		go l.fetch()
 	}
 
-	for k, v := range results {
-		chans := l.tasks[k]
-		if chans == nil {
-			panic(fmt.Errorf("task key missing: %v", k))
+	// fill the room left in the batch with low priority tasks
+	for k, waiters := range l.low {
+		if len(tasks) >= n {
+			break
 		}
-		res := &result[V]{
-			value: v,
+		if l.config.maxCost > 0 {
+			c := l.costFn(k)
+			if len(tasks) > 0 && cost+c > l.config.maxCost {
+				continue
+			}
+			cost += c
 		}
-		for _, ch := range chans {
-			ch <- res
+		tasks[k] = waiters
+		delete(l.low, k)
+	}
+	l.scheduleLow()
+
+	b := &batch[K, V]{tasks: tasks}
+	b.ctx, b.cancel = context.WithCancel(context.Background())
+	for _, waiters := range tasks {
+		for _, w := range waiters {
+			w.batch = b
 		}
-		delete(l.tasks, k)
+		b.live.Add(int64(len(waiters)))
 	}
+	return b
+}
 
-	// handle the requests with no result
-	if len(l.tasks) > 0 {
-		for k, chans := range l.tasks {
-			res := &result[V]{
+// stopTimer stops the timer for the pending batch, so the next key starts a
+// new one. It must be called with the lock held.
+func (l *Loader[K, V]) stopTimer() {
+	if l.timer != nil {
+		l.timer.Stop()
+		l.timer = nil
+		l.due = time.Time{}
+	}
+}
+
+// scheduleLow makes sure low priority tasks are fetched once they have waited
+// for the delay set by WithLowPriorityDelay, even if no other batch has room
+// for them. It must be called with the lock held.
+func (l *Loader[K, V]) scheduleLow() {
+	switch {
+	case len(l.low) == 0:
+		if l.lowTimer != nil {
+			l.lowTimer.Stop()
+			l.lowTimer = nil
+		}
+	case l.lowTimer == nil:
+		l.lowDue = time.Now().Add(l.config.lowDelay)
+		l.lowTimer = time.AfterFunc(l.config.lowDelay, l.fetch)
+	case !time.Now().Before(l.lowDue):
+		// the timer has fired, but there wasn't room for every task
+		l.lowTimer = time.AfterFunc(0, l.fetch)
+	}
+}
+
+// fetchAttempt fetches keys and sends the results to their waiters. It returns
+// the keys that failed and should be retried.
+func (l *Loader[K, V]) fetchAttempt(b *batch[K, V], keys []K, attempt int) []K {
+	tasks := b.tasks
+
+	var results map[K]Entry[V]
+	// a fetcher can fail with ErrCircuitOpen too, like a loader that fetches
+	// from another loader, so keep the breaker's own refusal apart
+	refused := l.breaker.allow()
+	err := refused
+	if refused == nil {
+		start := time.Now()
+		results, err = l.callFetcher(b.ctx, keys)
+		if b.ctx.Err() != nil {
+			// no one is waiting, so the outcome says nothing about the
+			// upstream
+			l.breaker.abort()
+			return nil
+		}
+		l.latency.observe(time.Since(start))
+	}
+	var keyErrs KeyErrors[K]
+	batchErr := err != nil && !errors.As(err, &keyErrs)
+	keyErrs = l.normalizeErrs(keyErrs)
+	if refused == nil {
+		l.breaker.done(batchErr)
+	}
+	if batchErr {
+		if l.config.retry.retryable(err, attempt) {
+			return keys
+		}
+		if l.fallback == nil {
+			sendError(tasks, err)
+			return nil
+		}
+		// the fallback may have the keys, even if the fetcher is down
+		keyErrs = make(KeyErrors[K], len(keys))
+		for _, k := range keys {
+			keyErrs[k] = err
+		}
+	}
+
+	l.deliver(tasks, results)
+
+	// handle the requests with no result, reporting the key each caller asked
+	// for rather than the normalized one
+	var retry, missing []K
+	for _, k := range keys {
+		if _, ok := tasks[k]; !ok {
+			continue
+		}
+		if kErr := keyErrs[k]; kErr != nil && l.config.retry.retryable(kErr, attempt) {
+			retry = append(retry, k)
+			continue
+		}
+		missing = append(missing, k)
+	}
+
+	if l.fallback != nil && len(missing) > 0 {
+		// the fallback shares the fetcher's rate limit, but not its breaker,
+		// so that it can serve keys while the circuit is open
+		l.limiter.wait(len(missing))
+		results, err := l.callFallback(b.ctx, missing)
+		if b.ctx.Err() != nil {
+			return nil
+		}
+		var fallbackErrs KeyErrors[K]
+		if errors.As(err, &fallbackErrs) {
+			fallbackErrs = l.normalizeErrs(fallbackErrs)
+		} else if err != nil {
+			fallbackErrs = make(KeyErrors[K], len(missing))
+			for _, k := range missing {
+				fallbackErrs[k] = err
+			}
+		}
+		l.deliver(tasks, results)
+
+		// errors from the fallback replace those from the fetcher, since it
+		// was tried last
+		for k, err := range fallbackErrs {
+			if keyErrs == nil {
+				keyErrs = make(KeyErrors[K], len(fallbackErrs))
+			}
+			keyErrs[k] = err
+		}
+	}
+
+	for _, k := range missing {
+		waiters, ok := tasks[k]
+		if !ok {
+			continue
+		}
+		kErr := keyErrs[k]
+		if kErr == nil {
+			kErr = ErrNotFound
+		}
+		for _, w := range waiters {
+			w.ch <- &result[V]{
 				err: &keyError[K]{
-					msg: "not found",
-					key: k,
+					err: kErr,
+					key: w.key,
 				},
 			}
-			for _, ch := range chans {
-				ch <- res
+		}
+		delete(tasks, k)
+	}
+	return retry
+}
+
+// deliver caches results and sends them to their waiters. Result keys are
+// normalized, since the upstream may spell them differently than the keys it
+// was asked for, and results for keys no one is waiting on are ignored.
+func (l *Loader[K, V]) deliver(tasks map[K][]*waiter[K, V], results map[K]Entry[V]) {
+	found := make(map[K]Entry[V], len(results))
+	for k, e := range results {
+		if nk := l.keyFn(k); tasks[nk] != nil {
+			found[nk] = e
+		}
+	}
+
+	if l.cache != nil && len(found) > 0 {
+		l.mu.Lock()
+		for k, e := range found {
+			l.cache.set(k, e)
+		}
+		l.mu.Unlock()
+	}
+
+	for k, e := range found {
+		res := &result[V]{
+			value: e.Value,
+		}
+		for _, w := range tasks[k] {
+			w.ch <- res
+		}
+		delete(tasks, k)
+	}
+}
+
+// normalizeErrs returns keyErrs with its keys normalized, like deliver does
+// for results.
+func (l *Loader[K, V]) normalizeErrs(keyErrs KeyErrors[K]) KeyErrors[K] {
+	if l.config.keyFunc == nil || keyErrs == nil {
+		return keyErrs
+	}
+	ret := make(KeyErrors[K], len(keyErrs))
+	for k, err := range keyErrs {
+		ret[l.keyFn(k)] = err
+	}
+	return ret
+}
+
+// callFallback calls the fallback set by WithFallback like callFetcher calls
+// the fetcher, but without hedging.
+func (l *Loader[K, V]) callFallback(ctx context.Context, keys []K) (map[K]Entry[V], error) {
+	return l.call(ctx, l.fallback, keys, 0)
+}
+
+// callFetcher calls the fetcher, and stops waiting for it once ctx is done. If
+// WithFetchTimeout is set, it also stops waiting once the timeout has passed.
+// Either way, the fetcher's context is cancelled.
+//
+// If WithHedging is set and the fetcher is slow, it calls the fetcher again,
+// returns whichever result comes first, and cancels the other call.
+func (l *Loader[K, V]) callFetcher(ctx context.Context, keys []K) (map[K]Entry[V], error) {
+	return l.call(ctx, l.fetcher, keys, l.config.hedge.delay(&l.latency))
+}
+
+// call does the work of callFetcher for fn, hedging after the given delay if
+// it is positive.
+func (l *Loader[K, V]) call(ctx context.Context, fn func(context.Context, []K) (map[K]Entry[V], error), keys []K, hedgeDelay time.Duration) (map[K]Entry[V], error) {
+	if l.config.fetchTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, l.config.fetchTimeout)
+		defer cancel()
+	}
+
+	type fetched struct {
+		results map[K]Entry[V]
+		err     error
+	}
+	// the call that loses, or is abandoned, is cancelled on return
+	callCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	ch := make(chan fetched, 2)
+	call := func() {
+		go func() {
+			results, err := recoverFetcher(callCtx, fn, keys)
+			ch <- fetched{results, err}
+		}()
+	}
+	call()
+
+	var hedge <-chan time.Time
+	if hedgeDelay > 0 {
+		timer := time.NewTimer(hedgeDelay)
+		defer timer.Stop()
+		hedge = timer.C
+	}
+
+	for {
+		select {
+		case f := <-ch:
+			return f.results, f.err
+		case <-hedge:
+			hedge = nil
+			if _, wait := l.limiter.reserve(len(keys), true); wait == 0 {
+				call()
+			}
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return nil, &TimeoutError{Op: "fetch", After: l.config.fetchTimeout}
 			}
-			delete(l.tasks, k)
+			return nil, ctx.Err()
 		}
 	}
+}
 
-	l.tick = nil
+// recoverFetcher calls fn, turning a panic into a *PanicError so that it fails
+// the batch instead of crashing the process.
+func recoverFetcher[K comparable, V any](ctx context.Context, fn func(context.Context, []K) (map[K]Entry[V], error), keys []K) (results map[K]Entry[V], err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			results = nil
+			err = &PanicError{
+				Value: r,
+				Stack: debug.Stack(),
+			}
+		}
+	}()
+	return fn(ctx, keys)
 }
 
-func (l *Loader[K, V]) sendError(err error) {
+func sendError[K comparable, V any](tasks map[K][]*waiter[K, V], err error) {
 	res := &result[V]{
 		err: err,
 	}
 
-	for _, chans := range l.tasks {
-		for _, ch := range chans {
-			ch <- res
+	for k, waiters := range tasks {
+		for _, w := range waiters {
+			w.ch <- res
 		}
+		delete(tasks, k)
 	}
 }
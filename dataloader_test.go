@@ -1,16 +1,55 @@
 package dataloader_test
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
 	"github.com/jsocol/dataloader"
 )
 
+// recorder is a fetcher that loads each key as its length, and records the
+// keys of each batch, sorted.
+type recorder struct {
+	mu      sync.Mutex
+	batches [][]string
+}
+
+func (r *recorder) fetch(keys []string) (map[string]int, error) {
+	r.record(keys)
+	ret := make(map[string]int, len(keys))
+	for _, k := range keys {
+		ret[k] = len(k)
+	}
+	return ret, nil
+}
+
+// record records a batch of keys, for fetchers that do more than fetch, and
+// returns how many batches there have been.
+func (r *recorder) record(keys []string) int {
+	keys = slices.Clone(keys)
+	slices.Sort(keys)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.batches = append(r.batches, keys)
+	return len(r.batches)
+}
+
+func (r *recorder) Batches() [][]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.batches)
+}
+
 func TestLoad(t *testing.T) {
 	var calls atomic.Int64
 	fetcher := func(keys []string) (map[string]int, error) {
@@ -221,6 +260,118 @@ func TestMaxBatchSize(t *testing.T) {
 	assert.Equal(t, int64(2), calls.Load())
 }
 
+func TestMaxBatchCost(t *testing.T) {
+	rec := &recorder{}
+	fetcher := rec.fetch
+
+	l := dataloader.New(fetcher,
+		dataloader.WithDelay(50*time.Millisecond),
+		dataloader.WithCostFunc(func(k string) int { return len(k) }),
+		dataloader.WithMaxBatchCost(10),
+	)
+
+	var wg sync.WaitGroup
+	for _, k := range []string{"aaaa", "bbbb", "cccccc", "dddddddddddd"} {
+		wg.Add(1)
+		go func(k string) {
+			defer wg.Done()
+			v, err := l.Load(k)
+			assert.NoError(t, err)
+			assert.Equal(t, len(k), v)
+		}(k)
+		time.Sleep(5 * time.Millisecond)
+	}
+	wg.Wait()
+
+	assert.ElementsMatch(t, [][]string{
+		{"aaaa", "bbbb"},
+		{"cccccc"},
+		{"dddddddddddd"},
+	}, rec.Batches())
+
+	assert.PanicsWithValue(t, "dataloader: WithCostFunc must be given a func(string) int", func() {
+		dataloader.New(fetcher, dataloader.WithCostFunc(func(k int) int { return k }))
+	})
+}
+
+func TestMaxPending(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	fetcher := func(keys []string) (map[string]int, error) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+		ret := make(map[string]int, len(keys))
+		for _, k := range keys {
+			ret[k] = len(k)
+		}
+		return ret, nil
+	}
+
+	l := dataloader.New(fetcher,
+		dataloader.WithDelay(10*time.Millisecond),
+		dataloader.WithMaxPending(2, dataloader.FailFast),
+	)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	for _, k := range []string{"foo", "quux"} {
+		go func(k string) {
+			defer wg.Done()
+			v, err := l.Load(k)
+			assert.NoError(t, err)
+			assert.Equal(t, len(k), v)
+		}(k)
+	}
+	<-started
+
+	_, err := l.Load("bar")
+	assert.ErrorIs(t, err, dataloader.ErrOverloaded)
+
+	close(release)
+	wg.Wait()
+
+	v, err := l.Load("bar")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, v)
+
+	gate := make(chan struct{})
+	blocking := dataloader.New(func(keys []string) (map[string]int, error) {
+		<-gate
+		return fetcher(keys)
+	}, dataloader.WithMaxPending(1, dataloader.Block))
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		v, err := blocking.Load("foo")
+		assert.NoError(t, err)
+		assert.Equal(t, 3, v)
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	// the first load holds the only slot, so the second waits for it
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		v, err := blocking.Load("quux")
+		assert.NoError(t, err)
+		assert.Equal(t, 4, v)
+	}()
+
+	select {
+	case <-done:
+		t.Error("load did not wait for a free slot")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(gate)
+	<-done
+	wg.Wait()
+}
+
 func TestLoadMany(t *testing.T) {
 	var calls atomic.Int64
 	fetcher := func(keys []string) (map[string]string, error) {
@@ -261,3 +412,430 @@ func TestLoadMany(t *testing.T) {
 
 	assert.Equal(t, int64(1), calls.Load())
 }
+
+func TestKeyFunc(t *testing.T) {
+	var calls atomic.Int64
+	fetcher := func(keys []string) (map[string]string, error) {
+		calls.Add(1)
+		assert.ElementsMatch(t, []string{"urn:isbn:978-1098118730", "urn:isbn:fake-book"}, keys)
+		return map[string]string{
+			"urn:isbn:978-1098118730": "The Staff Engineer's Path",
+		}, nil
+	}
+
+	l := dataloader.New(fetcher, dataloader.WithKeyFunc(strings.ToLower))
+
+	var wg sync.WaitGroup
+	wg.Add(3)
+
+	go func() {
+		defer wg.Done()
+		v, err := l.Load("URN:ISBN:978-1098118730")
+		assert.NoError(t, err)
+		assert.Equal(t, "The Staff Engineer's Path", v)
+	}()
+
+	go func() {
+		defer wg.Done()
+		v, err := l.Load("urn:isbn:978-1098118730")
+		assert.NoError(t, err)
+		assert.Equal(t, "The Staff Engineer's Path", v)
+	}()
+
+	go func() {
+		defer wg.Done()
+		_, err := l.Load("URN:ISBN:FAKE-BOOK")
+		kErr, ok := err.(dataloader.Error[string])
+		assert.True(t, ok, "error should have type dataloader.Error[string]")
+		assert.Equal(t, "URN:ISBN:FAKE-BOOK", kErr.Key())
+	}()
+
+	wg.Wait()
+
+	assert.Equal(t, int64(1), calls.Load())
+}
+
+func TestKeyFuncResultKeys(t *testing.T) {
+	fetcher := func(keys []string) (map[string]string, error) {
+		return map[string]string{
+			"URN:ISBN:978-1098118730": "The Staff Engineer's Path",
+			"urn:isbn:never-asked":    "Unrequested",
+		}, dataloader.KeyErrors[string]{
+			"URN:ISBN:BROKEN-BOOK": errors.New("broken"),
+		}
+	}
+
+	l := dataloader.New(fetcher, dataloader.WithKeyFunc(strings.ToLower))
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		v, err := l.Load("urn:isbn:978-1098118730")
+		assert.NoError(t, err)
+		assert.Equal(t, "The Staff Engineer's Path", v)
+	}()
+
+	go func() {
+		defer wg.Done()
+		_, err := l.Load("urn:isbn:broken-book")
+		assert.EqualError(t, err, "broken (urn:isbn:broken-book)")
+		assert.NotErrorIs(t, err, dataloader.ErrNotFound)
+	}()
+
+	wg.Wait()
+}
+
+type filter struct {
+	author string
+	tags   []string
+}
+
+func TestHashedKeys(t *testing.T) {
+	var calls atomic.Int64
+	hash := func(f filter) string {
+		return f.author + "|" + strings.Join(f.tags, ",")
+	}
+	fetcher := func(keys []filter) (map[string]int, error) {
+		calls.Add(1)
+		assert.Len(t, keys, 2)
+		ret := make(map[string]int, len(keys))
+		for _, k := range keys {
+			if k.author == "nobody" {
+				continue
+			}
+			ret[hash(k)] = len(k.tags)
+		}
+		return ret, nil
+	}
+
+	l := dataloader.NewHashed(fetcher, hash)
+
+	var wg sync.WaitGroup
+	wg.Add(3)
+
+	go func() {
+		defer wg.Done()
+		v, err := l.Load(filter{author: "tanya", tags: []string{"staff", "leadership"}})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, v)
+	}()
+
+	go func() {
+		defer wg.Done()
+		v, err := l.Load(filter{author: "tanya", tags: []string{"staff", "leadership"}})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, v)
+	}()
+
+	go func() {
+		defer wg.Done()
+		_, err := l.Load(filter{author: "nobody", tags: []string{"fiction"}})
+		assert.ErrorContains(t, err, "not found")
+		kErr, ok := err.(dataloader.Error[filter])
+		assert.True(t, ok, "error should have type dataloader.Error[filter]")
+		assert.Equal(t, "nobody", kErr.Key().author)
+	}()
+
+	wg.Wait()
+
+	assert.Equal(t, int64(1), calls.Load())
+}
+
+func TestHashedKeyFunc(t *testing.T) {
+	hash := func(f filter) string {
+		return f.author + "|" + strings.Join(f.tags, ",")
+	}
+	fetcher := func(keys []filter) (map[string]int, error) {
+		assert.Len(t, keys, 1)
+		ret := make(map[string]int, len(keys))
+		for _, k := range keys {
+			ret[hash(k)] = len(k.tags)
+		}
+		return ret, nil
+	}
+
+	l := dataloader.NewHashed(fetcher, hash, dataloader.WithKeyFunc(strings.ToLower))
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		v, err := l.Load(filter{author: "Tanya", tags: []string{"staff"}})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, v)
+	}()
+
+	go func() {
+		defer wg.Done()
+		v, err := l.Load(filter{author: "tanya", tags: []string{"Staff"}})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, v)
+	}()
+
+	wg.Wait()
+}
+
+func TestNotFoundIs(t *testing.T) {
+	fetcher := func(keys []string) (map[string]string, error) {
+		return nil, nil
+	}
+
+	l := dataloader.New(fetcher)
+
+	_, err := l.Load("missing")
+	assert.ErrorIs(t, err, dataloader.ErrNotFound)
+	assert.EqualError(t, err, "not found (missing)")
+}
+
+func TestManyLoader(t *testing.T) {
+	var calls atomic.Int64
+	fetcher := func(authorIDs []string) (map[string][]string, error) {
+		calls.Add(1)
+		assert.ElementsMatch(t, []string{"will-larson", "nobody"}, authorIDs)
+		return map[string][]string{
+			"will-larson": {"urn:isbn:978-1098149482", "urn:isbn:978-1736417911"},
+		}, nil
+	}
+
+	l := dataloader.NewMany(fetcher)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		vs, err := l.Load("will-larson")
+		assert.NoError(t, err)
+		assert.Len(t, vs, 2)
+	}()
+
+	go func() {
+		defer wg.Done()
+		vs, err := l.Load("nobody")
+		assert.NoError(t, err)
+		assert.NotNil(t, vs)
+		assert.Empty(t, vs)
+	}()
+
+	wg.Wait()
+
+	assert.Equal(t, int64(1), calls.Load())
+}
+
+func TestKeyErrors(t *testing.T) {
+	errUnavailable := errors.New("shard unavailable")
+	fetcher := func(keys []string) (map[string]string, error) {
+		return map[string]string{
+			"foo": "yes-foo",
+		}, dataloader.KeyErrors[string]{
+			"bar": errUnavailable,
+		}
+	}
+
+	l := dataloader.New(fetcher)
+
+	var wg sync.WaitGroup
+	wg.Add(3)
+
+	go func() {
+		defer wg.Done()
+		v, err := l.Load("foo")
+		assert.NoError(t, err)
+		assert.Equal(t, "yes-foo", v)
+	}()
+
+	go func() {
+		defer wg.Done()
+		_, err := l.Load("bar")
+		assert.ErrorIs(t, err, errUnavailable)
+		assert.EqualError(t, err, "shard unavailable (bar)")
+		kErr, ok := err.(dataloader.Error[string])
+		assert.True(t, ok, "error should have type dataloader.Error[string]")
+		assert.Equal(t, "bar", kErr.Key())
+	}()
+
+	go func() {
+		defer wg.Done()
+		_, err := l.Load("baz")
+		assert.ErrorIs(t, err, dataloader.ErrNotFound)
+	}()
+
+	wg.Wait()
+}
+
+func TestFetchErrorRecovers(t *testing.T) {
+	var calls atomic.Int64
+	fetcher := func(keys []string) (map[string]string, error) {
+		if calls.Add(1) == 1 {
+			return nil, errors.New("connection refused")
+		}
+		return map[string]string{"foo": "yes-foo"}, nil
+	}
+
+	l := dataloader.New(fetcher)
+
+	_, err := l.Load("foo")
+	assert.EqualError(t, err, "connection refused")
+
+	v, err := l.Load("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, "yes-foo", v)
+}
+
+func TestFetcherPanic(t *testing.T) {
+	fetcher := func(keys []string) (map[string]string, error) {
+		panic("oops")
+	}
+
+	l := dataloader.New(fetcher)
+
+	_, err := l.Load("foo")
+	var pErr *dataloader.PanicError
+	assert.ErrorAs(t, err, &pErr)
+	assert.Equal(t, "oops", pErr.Value)
+	assert.NotEmpty(t, pErr.Stack)
+	assert.EqualError(t, err, "fetcher panicked: oops")
+}
+
+func TestErrorKey(t *testing.T) {
+	l := dataloader.New(func(keys []key) (map[key]string, error) {
+		return nil, nil
+	})
+
+	_, err := l.Load(key{major: "resource", minor: "missing"})
+	k, ok := dataloader.ErrorKey(fmt.Errorf("loading resource: %w", err))
+	assert.True(t, ok)
+	assert.Equal(t, key{major: "resource", minor: "missing"}, k)
+
+	_, ok = dataloader.ErrorKey(errors.New("connection refused"))
+	assert.False(t, ok)
+}
+
+func TestFallback(t *testing.T) {
+	errShard := errors.New("shard unavailable")
+	fetcher := func(keys []string) (map[string]string, error) {
+		ret := make(map[string]string, len(keys))
+		kErrs := dataloader.KeyErrors[string]{}
+		for _, k := range keys {
+			switch k {
+			case "current":
+				ret[k] = "primary"
+			case "sharded":
+				kErrs[k] = errShard
+			}
+		}
+		return ret, kErrs
+	}
+
+	rec := &recorder{}
+	fallback := func(keys []string) (map[string]string, error) {
+		rec.record(keys)
+
+		ret := make(map[string]string, len(keys))
+		for _, k := range keys {
+			if k != "missing" {
+				ret[k] = "archive"
+			}
+		}
+		return ret, nil
+	}
+
+	l := dataloader.New(fetcher, dataloader.WithFallback(fallback))
+
+	var mu sync.Mutex
+	vals := map[string]string{}
+	errs := map[string]error{}
+	var wg sync.WaitGroup
+	for _, k := range []string{"current", "sharded", "archived", "missing"} {
+		wg.Add(1)
+		go func(k string) {
+			defer wg.Done()
+			v, err := l.Load(k)
+			mu.Lock()
+			defer mu.Unlock()
+			vals[k] = v
+			errs[k] = err
+		}(k)
+	}
+	wg.Wait()
+
+	assert.Equal(t, [][]string{{"archived", "missing", "sharded"}}, rec.Batches())
+	assert.Equal(t, "primary", vals["current"])
+	assert.Equal(t, "archive", vals["sharded"])
+	assert.Equal(t, "archive", vals["archived"])
+	assert.NoError(t, errs["current"])
+	assert.NoError(t, errs["sharded"])
+	assert.NoError(t, errs["archived"])
+	assert.ErrorIs(t, errs["missing"], dataloader.ErrNotFound)
+
+	errArchive := errors.New("archive offline")
+	failing := dataloader.New(fetcher, dataloader.WithFallback(func(keys []string) (map[string]string, error) {
+		return nil, errArchive
+	}))
+	_, err := failing.Load("archived")
+	assert.ErrorIs(t, err, errArchive)
+	k, ok := dataloader.ErrorKey(err)
+	assert.True(t, ok)
+	assert.Equal(t, "archived", k)
+
+	assert.PanicsWithValue(t, "dataloader: WithFallback must be given a Fetcher[string, string] or ContextFetcher[string, string]", func() {
+		dataloader.New(fetcher, dataloader.WithFallback(func(keys []int) (map[int]string, error) {
+			return nil, nil
+		}))
+	})
+}
+
+func TestFallbackBatchError(t *testing.T) {
+	errDown := errors.New("primary down")
+	fetcher := func(keys []string) (map[string]string, error) {
+		return nil, errDown
+	}
+	fallback := func(keys []string) (map[string]string, error) {
+		return map[string]string{
+			"archived":    "archive",
+			"never-asked": "unrequested",
+		}, nil
+	}
+
+	l := dataloader.New(fetcher,
+		dataloader.WithFallback(fallback),
+		dataloader.WithCircuitBreaker(dataloader.BreakerPolicy{MinBatches: 1, Cooldown: time.Hour}),
+	)
+
+	v, err := l.Load("archived")
+	assert.NoError(t, err)
+	assert.Equal(t, "archive", v)
+
+	// the first batch opened the circuit, and the fallback serves the next
+	v, err = l.Load("archived")
+	assert.NoError(t, err)
+	assert.Equal(t, "archive", v)
+
+	_, err = l.Load("missing")
+	assert.ErrorIs(t, err, dataloader.ErrCircuitOpen)
+	k, ok := dataloader.ErrorKey(err)
+	assert.True(t, ok)
+	assert.Equal(t, "missing", k)
+}
+
+func TestFallbackContext(t *testing.T) {
+	fetcher := func(keys []string) (map[string]string, error) {
+		return nil, nil
+	}
+	fallback := func(ctx context.Context, keys []string) (map[string]string, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	l := dataloader.New(fetcher,
+		dataloader.WithFallbackContext(fallback),
+		dataloader.WithFetchTimeout(10*time.Millisecond),
+	)
+
+	_, err := l.Load("slow")
+	var tErr *dataloader.TimeoutError
+	assert.ErrorAs(t, err, &tErr)
+}
@@ -0,0 +1,92 @@
+package dataloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrNotFound is the underlying error when the fetcher does not return a
+	// value for a key. Use errors.Is to check for it.
+	ErrNotFound = errors.New("not found")
+
+	// ErrOverloaded means a loader, or the upstream behind it, is shedding
+	// load. Fetchers may return or wrap it when their upstream asks them to
+	// back off, for example with a 429 or 503 status.
+	ErrOverloaded = errors.New("overloaded")
+
+	// ErrCircuitOpen is returned for keys in a batch that the circuit breaker
+	// set by WithCircuitBreaker did not send to the fetcher, because recent
+	// batches have been failing.
+	ErrCircuitOpen = errors.New("circuit open")
+)
+
+type Error[K any] interface {
+	error
+	Key() K
+}
+
+type keyError[K any] struct {
+	err error
+	key K
+}
+
+func (k *keyError[K]) Error() string {
+	return fmt.Sprintf("%s (%v)", k.err, k.key)
+}
+
+func (k *keyError[K]) Key() K {
+	return k.key
+}
+
+func (k *keyError[K]) Unwrap() error {
+	return k.err
+}
+
+func (k *keyError[K]) untypedKey() any {
+	return k.key
+}
+
+// ErrorKey returns the key from the first Error[K] in err's chain, whatever
+// the type of the key. It is meant for code that reports errors from loaders
+// of many types, like the error mapping in the integration packages.
+func ErrorKey(err error) (any, bool) {
+	var kErr interface{ untypedKey() any }
+	if errors.As(err, &kErr) {
+		return kErr.untypedKey(), true
+	}
+	return nil, false
+}
+
+// PanicError is returned to every caller in a batch when the fetcher panics.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("fetcher panicked: %v", e.Value)
+}
+
+// TimeoutError is returned when a fetch or load takes longer than the timeout
+// set by WithFetchTimeout or WithLoadTimeout. It is a context.DeadlineExceeded,
+// and, like net.Error, has a Timeout method.
+type TimeoutError struct {
+	// Op is "fetch" or "load".
+	Op    string
+	After time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("dataloader: %s timed out after %s", e.Op, e.After)
+}
+
+func (e *TimeoutError) Timeout() bool {
+	return true
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
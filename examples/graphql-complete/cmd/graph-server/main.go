@@ -10,6 +10,7 @@ import (
 	"github.com/99designs/gqlgen/graphql/handler"
 	"github.com/99designs/gqlgen/graphql/playground"
 	"github.com/jsocol/dataloader"
+	"github.com/jsocol/dataloader/gqlerr"
 	"github.com/jsocol/shutdown"
 
 	"github.com/jsocol/dataloader/examples/graphql-complete/fetchers/books"
@@ -48,11 +49,12 @@ func main() {
 	bookFetcher := books.New(resourceAddr)
 
 	resolver := &graph.Resolver{
-		People: dataloader.New(peopleFetcher.Fetch),
-		Books:  dataloader.New(bookFetcher.Fetch),
+		People: dataloader.NewContext(peopleFetcher.Fetch),
+		Books:  dataloader.NewContext(bookFetcher.Fetch),
 	}
 
 	gqlsrv := handler.NewDefaultServer(graph.NewExecutableSchema(graph.Config{Resolvers: resolver}))
+	gqlsrv.SetErrorPresenter(gqlerr.Presenter)
 
 	mux := http.NewServeMux()
 	srv := http.Server{
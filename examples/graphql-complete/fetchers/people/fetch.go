@@ -2,11 +2,9 @@ package people
 
 import (
 	"context"
-	"encoding/json"
-	"io"
 	"log/slog"
-	"net/http"
-	"net/url"
+
+	"github.com/jsocol/dataloader/httpfetch"
 
 	"github.com/jsocol/dataloader/examples/graphql-complete/fetchers"
 	"github.com/jsocol/dataloader/examples/graphql-complete/graph/model"
@@ -17,13 +15,12 @@ type person struct {
 	Name string
 }
 
-type doer interface {
-	Do(*http.Request) (*http.Response, error)
+func personID(p person) string {
+	return p.ID
 }
 
 type fetcher struct {
-	baseAddr string
-	client   doer
+	people *httpfetch.Fetcher[string, person]
 }
 
 func New(addr string) *fetcher {
@@ -31,46 +28,22 @@ func New(addr string) *fetcher {
 	u.Path = "people"
 
 	return &fetcher{
-		baseAddr: u.String(),
-		client:   http.DefaultClient,
+		people: httpfetch.New(u.String(), personID),
 	}
 }
 
-func (f *fetcher) Fetch(ids []string) (map[string]*model.Person, error) {
-	ctx := context.TODO()
+func (f *fetcher) Fetch(ctx context.Context, ids []string) (map[string]*model.Person, error) {
 
 	slog.DebugContext(ctx, "fetching people", "ids", ids)
 
-	qv := url.Values{}
-	for _, id := range ids {
-		qv.Add("id", id)
-	}
-	u := fetchers.MustParse(f.baseAddr)
-	u.RawQuery = qv.Encode()
-
-	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := f.client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-
-	bodyReader := io.LimitReader(resp.Body, 4<<20)
-	defer resp.Body.Close()
-
-	jr := json.NewDecoder(bodyReader)
-	people := make([]person, 0, len(ids))
-	err = jr.Decode(&people)
+	people, err := f.people.FetchContext(ctx, ids)
 	if err != nil {
 		return nil, err
 	}
 
 	ret := make(map[string]*model.Person, len(people))
-	for _, p := range people {
-		ret[p.ID] = &model.Person{
+	for id, p := range people {
+		ret[id] = &model.Person{
 			ID:   p.ID,
 			Name: p.Name,
 		}
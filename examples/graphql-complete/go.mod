@@ -5,6 +5,7 @@ go 1.22.5
 require (
 	github.com/99designs/gqlgen v0.17.49
 	github.com/jsocol/dataloader v0.0.0-20240803220548-c3ea55cf404e
+	github.com/jsocol/dataloader/gqlerr v0.0.0-00010101000000-000000000000
 	github.com/jsocol/shutdown v0.1.1
 	github.com/vektah/gqlparser/v2 v2.5.16
 )
@@ -26,3 +27,8 @@ require (
 	golang.org/x/tools v0.22.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+replace (
+	github.com/jsocol/dataloader => ../..
+	github.com/jsocol/dataloader/gqlerr => ../../gqlerr
+)
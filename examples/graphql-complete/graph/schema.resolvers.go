@@ -8,11 +8,10 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"slices"
 	"sync"
 
 	"github.com/99designs/gqlgen/graphql"
-	"github.com/vektah/gqlparser/v2/ast"
-	"github.com/vektah/gqlparser/v2/gqlerror"
 
 	"github.com/jsocol/dataloader/examples/graphql-complete/graph/model"
 )
@@ -23,40 +22,31 @@ func (r *bookResolver) Authors(ctx context.Context, book *model.Book) ([]*model.
 		return book.Authors, nil
 	}
 
-	var mu sync.Mutex
-
-	authors := make([]*model.Person, 0, len(book.AuthorIDs))
-
-	// For performance reasons, we should try to fetch these Authors in
-	// parallel. Normally this would create N queries, one for each author.
-	// restdataloader collapses these requests, because they are made in rapid
-	// succession.
+	// The authors are loaded concurrently, so the People loader fetches them,
+	// and the authors of every other book in the query, in one batch. An
+	// author that fails is reported at its own index, and left out.
+	authors := make([]*model.Person, len(book.AuthorIDs))
 	var wg sync.WaitGroup
+	wg.Add(len(book.AuthorIDs))
 	for i, id := range book.AuthorIDs {
-		wg.Add(1)
-		go func(i int, id string) {
+		go func() {
 			defer wg.Done()
 
-			author, err := r.Resolver.People.Load(id)
+			author, err := r.Resolver.People.LoadContext(ctx, id)
 			if err != nil {
 				slog.ErrorContext(ctx, "error loading author", "id", id, "error", err)
-				path := graphql.GetPath(ctx)
-				path = append(path, ast.PathIndex(i))
-				graphql.AddError(ctx, &gqlerror.Error{
-					Path:    path,
-					Message: err.Error(),
-				})
+				graphql.AddError(graphql.WithPathContext(ctx, graphql.NewPathWithIndex(i)), err)
 				return
 			}
-
-			mu.Lock()
-			defer mu.Unlock()
-			authors = append(authors, author)
-		}(i, id)
+			authors[i] = author
+		}()
 	}
-
 	wg.Wait()
 
+	authors = slices.DeleteFunc(authors, func(p *model.Person) bool {
+		return p == nil
+	})
+
 	slog.DebugContext(ctx, "resolved authors", "book", book.ID, "authors", authors)
 
 	return authors, nil
@@ -69,12 +59,12 @@ func (r *bookResolver) Editors(ctx context.Context, obj *model.Book) ([]*model.P
 
 // Person is the resolver for the person field.
 func (r *queryResolver) Person(ctx context.Context, id string) (*model.Person, error) {
-	return r.People.Load(id)
+	return r.People.LoadContext(ctx, id)
 }
 
 // Book is the resolver for the book field.
 func (r *queryResolver) Book(ctx context.Context, id string) (*model.Book, error) {
-	return r.Books.Load(id)
+	return r.Books.LoadContext(ctx, id)
 }
 
 // Book returns BookResolver implementation.
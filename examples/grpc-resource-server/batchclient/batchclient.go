@@ -0,0 +1,45 @@
+// Package batchclient collapses concurrent single-resource gRPC calls, like
+// GetBook, into one batch call, like BatchGetBooks, on the client side.
+package batchclient
+
+import (
+	"context"
+	"time"
+
+	"github.com/jsocol/dataloader"
+	"github.com/jsocol/dataloader/grpcerr"
+)
+
+// DefaultTimeout is the deadline of each batch call, unless New is given
+// another with dataloader.WithFetchTimeout.
+const DefaultTimeout = 10 * time.Second
+
+// BatchFunc calls a batch RPC for keys, and returns the values it found.
+type BatchFunc[K comparable, V any] func(ctx context.Context, keys []K) (map[K]V, error)
+
+// Client turns each call to Get into a Load, and fulfills the loads with as
+// few calls to a BatchFunc as possible.
+type Client[K comparable, V any] struct {
+	loader *dataloader.Loader[K, V]
+}
+
+// New creates a Client for batch. Options are passed through to the
+// dataloader, for example to set the batch delay or maximum size.
+//
+// The batch is shared by many callers, so it cannot use any one of their
+// contexts. Instead, its context has a deadline of DefaultTimeout, and is
+// cancelled if every caller waiting on the batch goes away.
+func New[K comparable, V any](batch BatchFunc[K, V], opts ...dataloader.Option) *Client[K, V] {
+	opts = append([]dataloader.Option{dataloader.WithFetchTimeout(DefaultTimeout)}, opts...)
+	return &Client[K, V]{
+		loader: dataloader.NewContext(dataloader.ContextFetcher[K, V](batch), opts...),
+	}
+}
+
+// Get loads a single value. Keys that the batch call did not return fail with
+// a codes.NotFound status, like the single-resource RPC would, and errors
+// from the batch call keep their status. Get stops waiting when ctx is done.
+func (c *Client[K, V]) Get(ctx context.Context, key K) (V, error) {
+	v, err := c.loader.LoadContext(ctx, key)
+	return v, grpcerr.Error(err)
+}
@@ -0,0 +1,44 @@
+package batchclient
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+
+	"github.com/jsocol/dataloader/examples/grpc-resource-server/proto"
+)
+
+// BookServiceClient is a proto.BookServiceClient that sends concurrent GetBook
+// calls as one BatchGetBooks call. All other methods are passed through.
+type BookServiceClient struct {
+	proto.BookServiceClient
+	books *Client[string, *proto.Book]
+}
+
+func NewBookServiceClient(cc grpc.ClientConnInterface) *BookServiceClient {
+	c := proto.NewBookServiceClient(cc)
+
+	batch := func(ctx context.Context, ids []string) (map[string]*proto.Book, error) {
+		resp, err := c.BatchGetBooks(ctx, &proto.BatchGetBooksRequest{Ids: ids})
+		if err != nil {
+			return nil, err
+		}
+
+		ret := make(map[string]*proto.Book, len(resp.Books))
+		for _, b := range resp.Books {
+			ret[b.Id] = b
+		}
+		return ret, nil
+	}
+
+	return &BookServiceClient{
+		BookServiceClient: c,
+		books:             New(batch),
+	}
+}
+
+// GetBook loads a book through BatchGetBooks. Call options are ignored, since
+// the call is shared with other callers.
+func (c *BookServiceClient) GetBook(ctx context.Context, in *proto.GetBookRequest, _ ...grpc.CallOption) (*proto.Book, error) {
+	return c.books.Get(ctx, in.Id)
+}
@@ -12,6 +12,7 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 
+	"github.com/jsocol/dataloader/examples/grpc-resource-server/batchclient"
 	"github.com/jsocol/dataloader/examples/grpc-resource-server/proto"
 )
 
@@ -33,7 +34,8 @@ func main() {
 	}
 	defer conn.Close()
 
-	c := proto.NewBookServiceClient(conn)
+	// GetBook calls on this client are sent to the server as BatchGetBooks
+	var c proto.BookServiceClient = batchclient.NewBookServiceClient(conn)
 
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	defer cancel()
@@ -59,7 +61,7 @@ func main() {
 			defer wg.Done()
 			b, err := c.GetBook(ctx, &proto.GetBookRequest{Id: id})
 			if err != nil {
-				slog.WarnContext(ctx, "error fetching book", "book", id)
+				slog.WarnContext(ctx, "error fetching book", "book", id, "error", err)
 				return
 			}
 			results[i] = book{
@@ -3,8 +3,8 @@ module github.com/jsocol/dataloader/examples/grpc-resource-server
 go 1.22.5
 
 require (
-	github.com/Masterminds/squirrel v1.5.4
 	github.com/jsocol/dataloader v0.0.0-20240803220548-c3ea55cf404e
+	github.com/jsocol/dataloader/grpcerr v0.0.0-00010101000000-000000000000
 	github.com/jsocol/shutdown v0.1.1
 	google.golang.org/grpc v1.65.0
 	google.golang.org/grpc/cmd/protoc-gen-go-grpc v1.5.1
@@ -16,8 +16,6 @@ require (
 	github.com/dustin/go-humanize v1.0.1 // indirect
 	github.com/google/uuid v1.6.0 // indirect
 	github.com/hashicorp/golang-lru/v2 v2.0.7 // indirect
-	github.com/lann/builder v0.0.0-20180802200727-47ae307949d0 // indirect
-	github.com/lann/ps v0.0.0-20150810152359-62de8c46ede0 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
 	github.com/ncruces/go-strftime v0.1.9 // indirect
 	github.com/remyoudompheng/bigfft v0.0.0-20230129092748-24d4a6f8daec // indirect
@@ -32,3 +30,8 @@ require (
 	modernc.org/strutil v1.2.0 // indirect
 	modernc.org/token v1.1.0 // indirect
 )
+
+replace (
+	github.com/jsocol/dataloader => ../..
+	github.com/jsocol/dataloader/grpcerr => ../../grpcerr
+)
@@ -2,17 +2,19 @@ package server
 
 import (
 	"context"
+	"errors"
 	"log/slog"
+	"sync"
 
 	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 
 	"github.com/jsocol/dataloader"
 	"github.com/jsocol/dataloader/examples/grpc-resource-server/proto"
+	"github.com/jsocol/dataloader/grpcerr"
 )
 
 type bookLoader interface {
-	Load(string) (*proto.Book, error)
+	LoadContext(context.Context, string) (*proto.Book, error)
 }
 
 type Server struct {
@@ -21,13 +23,54 @@ type Server struct {
 }
 
 func (s *Server) GetBook(ctx context.Context, in *proto.GetBookRequest) (*proto.Book, error) {
-	book, err := s.Books.Load(in.Id)
+	book, err := s.Books.LoadContext(ctx, in.Id)
 	if err != nil {
-		if err == dataloader.NotFound {
-			return nil, status.Errorf(codes.NotFound, "book not found: %s", in.Id)
+		st := grpcerr.Status(err)
+		if st.Code() == codes.Internal {
+			slog.ErrorContext(ctx, "error loading book", "book", in.Id, "error", err)
 		}
-		slog.ErrorContext(ctx, "error loading book", "book", in.Id, "error", err)
-		return nil, status.Errorf(codes.Internal, "unknown error")
+		return nil, st.Err()
 	}
 	return book, nil
 }
+
+// BatchGetBooks loads each book through the same loader as GetBook, so these
+// are batched together with any concurrent GetBook calls. Books that do not
+// exist are left out of the response.
+func (s *Server) BatchGetBooks(ctx context.Context, in *proto.BatchGetBooksRequest) (*proto.BatchGetBooksResponse, error) {
+	var mu sync.Mutex
+	var failed error
+	resp := &proto.BatchGetBooksResponse{
+		Books: make([]*proto.Book, 0, len(in.Ids)),
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(len(in.Ids))
+	for _, id := range in.Ids {
+		go func(id string) {
+			defer wg.Done()
+			book, err := s.Books.LoadContext(ctx, id)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if errors.Is(err, dataloader.ErrNotFound) {
+				return
+			}
+			if err != nil {
+				slog.ErrorContext(ctx, "error loading book", "book", id, "error", err)
+				if failed == nil {
+					failed = err
+				}
+				return
+			}
+			resp.Books = append(resp.Books, book)
+		}(id)
+	}
+	wg.Wait()
+
+	if failed != nil {
+		return nil, grpcerr.Error(failed)
+	}
+	return resp, nil
+}
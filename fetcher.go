@@ -0,0 +1,44 @@
+package dataloader
+
+import "fmt"
+
+// FromSlice adapts a function that returns a list of values, like most REST
+// endpoints, into a Fetcher. keyOf is called on each value to find its key.
+// Keys missing from the list are not found.
+func FromSlice[K comparable, V any](fn func([]K) ([]V, error), keyOf func(V) K) Fetcher[K, V] {
+	return func(keys []K) (map[K]V, error) {
+		values, err := fn(keys)
+		if err != nil {
+			return nil, err
+		}
+
+		ret := make(map[K]V, len(values))
+		for _, v := range values {
+			ret[keyOf(v)] = v
+		}
+		return ret, nil
+	}
+}
+
+// FromPositional adapts a function that returns exactly one value for each
+// key, in the same order as the keys, into a Fetcher. If fn returns a
+// different number of values than it was given keys, the whole batch fails,
+// since there is no way to tell which value belongs to which key.
+func FromPositional[K comparable, V any](fn func([]K) ([]V, error)) Fetcher[K, V] {
+	return func(keys []K) (map[K]V, error) {
+		values, err := fn(keys)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(values) != len(keys) {
+			return nil, fmt.Errorf("dataloader: got %d values for %d keys", len(values), len(keys))
+		}
+
+		ret := make(map[K]V, len(values))
+		for i, v := range values {
+			ret[keys[i]] = v
+		}
+		return ret, nil
+	}
+}
@@ -0,0 +1,70 @@
+package dataloader_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jsocol/dataloader"
+)
+
+type book struct {
+	ID    string
+	Title string
+}
+
+func TestFromSlice(t *testing.T) {
+	list := func(ids []string) ([]book, error) {
+		return []book{
+			{ID: "urn:isbn:978-1098118730", Title: "The Staff Engineer's Path"},
+		}, nil
+	}
+
+	fetcher := dataloader.FromSlice(list, func(b book) string { return b.ID })
+	got, err := fetcher([]string{"urn:isbn:978-1098118730", "urn:isbn:fake-book"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]book{
+		"urn:isbn:978-1098118730": {ID: "urn:isbn:978-1098118730", Title: "The Staff Engineer's Path"},
+	}, got)
+}
+
+func TestFromSliceKeyFunc(t *testing.T) {
+	list := func(ids []string) ([]book, error) {
+		return []book{
+			{ID: "URN:ISBN:978-1098118730", Title: "The Staff Engineer's Path"},
+			{ID: "urn:isbn:never-asked", Title: "Unrequested"},
+		}, nil
+	}
+
+	// the loader matches the upstream's spelling of the key, and ignores
+	// values no one asked for
+	l := dataloader.New(dataloader.FromSlice(list, func(b book) string { return b.ID }), dataloader.WithKeyFunc(strings.ToLower))
+	v, err := l.Load("urn:isbn:978-1098118730")
+	assert.NoError(t, err)
+	assert.Equal(t, "The Staff Engineer's Path", v.Title)
+}
+
+func TestFromPositional(t *testing.T) {
+	upper := func(keys []string) ([]string, error) {
+		ret := make([]string, 0, len(keys))
+		for _, k := range keys {
+			ret = append(ret, strings.ToUpper(k))
+		}
+		return ret, nil
+	}
+
+	fetcher := dataloader.FromPositional(upper)
+	got, err := fetcher([]string{"foo", "bar"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"foo": "FOO", "bar": "BAR"}, got)
+
+	short := func(keys []string) ([]string, error) {
+		return []string{"FOO"}, nil
+	}
+
+	fetcher = dataloader.FromPositional(short)
+	got, err = fetcher([]string{"foo", "bar"})
+	assert.EqualError(t, err, "dataloader: got 1 values for 2 keys")
+	assert.Nil(t, got)
+}
@@ -0,0 +1,26 @@
+module github.com/jsocol/dataloader/gqlerr
+
+go 1.22.5
+
+require (
+	github.com/99designs/gqlgen v0.17.49
+	github.com/jsocol/dataloader v0.1.0
+	github.com/stretchr/testify v1.9.0
+	github.com/vektah/gqlparser/v2 v2.5.16
+)
+
+require (
+	github.com/agnivade/levenshtein v1.1.1 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/gorilla/websocket v1.5.0 // indirect
+	github.com/hashicorp/golang-lru/v2 v2.0.7 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/sosodev/duration v1.3.1 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+// The replace only applies inside this repository. The required version of
+// dataloader must be a tagged release that has everything this module uses,
+// since that is what consumers get. See "Releasing" in the README.
+replace github.com/jsocol/dataloader => ../
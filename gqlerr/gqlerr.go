@@ -0,0 +1,47 @@
+// Package gqlerr maps errors from loaders to GraphQL errors for gqlgen.
+//
+// It is a separate module, so that using dataloader does not require gqlgen.
+package gqlerr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+
+	"github.com/jsocol/dataloader"
+	"github.com/jsocol/dataloader/internal/errclass"
+)
+
+// Presenter is a graphql.ErrorPresenterFunc, for use with SetErrorPresenter
+// on a gqlgen server. It adds a "code" extension with the kind of error, like
+// "NOT_FOUND" or "TIMEOUT", and a "key" extension with the failing key, if
+// there is one. A message is hidden if errclass.Exposed says it is unsafe.
+//
+// Errors that are already GraphQL errors, rather than errors returned by a
+// resolver, are presented as gqlgen would by default.
+func Presenter(ctx context.Context, err error) *gqlerror.Error {
+	gErr := graphql.DefaultErrorPresenter(ctx, err)
+
+	var existing *gqlerror.Error
+	if errors.As(err, &existing) && existing.Err == nil {
+		return gErr
+	}
+
+	class := errclass.Of(err)
+	if !class.Exposed() {
+		gErr.Message = "internal error"
+	}
+
+	if gErr.Extensions == nil {
+		gErr.Extensions = make(map[string]interface{})
+	}
+	gErr.Extensions["code"] = class.String()
+	if key, ok := dataloader.ErrorKey(err); ok {
+		gErr.Extensions["key"] = fmt.Sprint(key)
+	}
+
+	return gErr
+}
@@ -0,0 +1,42 @@
+package gqlerr_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/stretchr/testify/assert"
+	"github.com/vektah/gqlparser/v2/ast"
+
+	"github.com/jsocol/dataloader"
+	"github.com/jsocol/dataloader/gqlerr"
+)
+
+func TestPresenter(t *testing.T) {
+	l := dataloader.New(func(keys []string) (map[string]string, error) {
+		return nil, dataloader.KeyErrors[string]{
+			"urn:person:broken": errors.New("shard 3 unavailable"),
+		}
+	})
+
+	ctx := graphql.WithPathContext(context.Background(), graphql.NewPathWithField("authors"))
+	ctx = graphql.WithPathContext(ctx, graphql.NewPathWithIndex(1))
+
+	_, err := l.Load("urn:person:fake")
+	gErr := gqlerr.Presenter(ctx, err)
+	assert.Equal(t, "not found (urn:person:fake)", gErr.Message)
+	assert.Equal(t, ast.Path{ast.PathName("authors"), ast.PathIndex(1)}, gErr.Path)
+	assert.Equal(t, map[string]interface{}{
+		"code": "NOT_FOUND",
+		"key":  "urn:person:fake",
+	}, gErr.Extensions)
+
+	_, err = l.Load("urn:person:broken")
+	gErr = gqlerr.Presenter(ctx, err)
+	assert.Equal(t, "internal error", gErr.Message)
+	assert.Equal(t, map[string]interface{}{
+		"code": "INTERNAL",
+		"key":  "urn:person:broken",
+	}, gErr.Extensions)
+}
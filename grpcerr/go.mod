@@ -0,0 +1,25 @@
+module github.com/jsocol/dataloader/grpcerr
+
+go 1.22.5
+
+require (
+	github.com/jsocol/dataloader v0.1.0
+	github.com/stretchr/testify v1.9.0
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20240604185151-ef581f913117
+	google.golang.org/grpc v1.65.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/net v0.26.0 // indirect
+	golang.org/x/sys v0.22.0 // indirect
+	golang.org/x/text v0.16.0 // indirect
+	google.golang.org/protobuf v1.34.2 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+// The replace only applies inside this repository. The required version of
+// dataloader must be a tagged release that has everything this module uses,
+// since that is what consumers get. See "Releasing" in the README.
+replace github.com/jsocol/dataloader => ../
@@ -0,0 +1,89 @@
+// Package grpcerr maps errors from loaders to gRPC statuses.
+//
+// It is a separate module, so that using dataloader does not require gRPC.
+package grpcerr
+
+import (
+	"fmt"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/jsocol/dataloader"
+	"github.com/jsocol/dataloader/internal/errclass"
+)
+
+// Domain is the domain of the ErrorInfo details attached by Status.
+const Domain = "github.com/jsocol/dataloader"
+
+// Status returns the gRPC status for err:
+//
+//   - NotFound, for keys that were not found
+//   - DeadlineExceeded, for timeouts
+//   - Canceled, for cancellations
+//   - ResourceExhausted, for dataloader.ErrOverloaded
+//   - Unavailable, for dataloader.ErrCircuitOpen
+//   - Internal, for panics and any other error
+//
+// Errors that already carry a status, like errors from a batch RPC, keep it.
+// Otherwise the status has an ErrorInfo detail, whose reason is the kind of
+// error, like "NOT_FOUND", and whose metadata has the failing key, if there
+// is one. Messages that errclass.Exposed hides are replaced with a generic
+// one.
+func Status(err error) *status.Status {
+	if err == nil {
+		return status.New(codes.OK, "")
+	}
+	if s, ok := status.FromError(err); ok {
+		return s
+	}
+
+	class := errclass.Of(err)
+
+	var code codes.Code
+	switch class {
+	case errclass.NotFound:
+		code = codes.NotFound
+	case errclass.Timeout:
+		code = codes.DeadlineExceeded
+	case errclass.Canceled:
+		code = codes.Canceled
+	case errclass.Overloaded:
+		code = codes.ResourceExhausted
+	case errclass.Unavailable:
+		code = codes.Unavailable
+	default:
+		code = codes.Internal
+	}
+
+	msg := "internal error"
+	if class.Exposed() {
+		msg = err.Error()
+	}
+
+	info := &errdetails.ErrorInfo{
+		Reason: class.String(),
+		Domain: Domain,
+	}
+	if key, ok := dataloader.ErrorKey(err); ok {
+		info.Metadata = map[string]string{
+			"key": fmt.Sprint(key),
+		}
+	}
+
+	s := status.New(code, msg)
+	if withInfo, dErr := s.WithDetails(info); dErr == nil {
+		s = withInfo
+	}
+	return s
+}
+
+// Error is like Status, but returns an error for a gRPC handler to return. It
+// returns nil if err is nil.
+func Error(err error) error {
+	if err == nil {
+		return nil
+	}
+	return Status(err).Err()
+}
@@ -0,0 +1,53 @@
+package grpcerr_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/jsocol/dataloader"
+	"github.com/jsocol/dataloader/grpcerr"
+)
+
+func TestStatus(t *testing.T) {
+	l := dataloader.New(func(keys []string) (map[string]string, error) {
+		return nil, nil
+	})
+	_, notFound := l.Load("urn:isbn:fake-book")
+
+	tests := []struct {
+		err  error
+		code codes.Code
+		msg  string
+	}{
+		{notFound, codes.NotFound, "not found (urn:isbn:fake-book)"},
+		{context.DeadlineExceeded, codes.DeadlineExceeded, "context deadline exceeded"},
+		{context.Canceled, codes.Canceled, "context canceled"},
+		{dataloader.ErrOverloaded, codes.ResourceExhausted, "overloaded"},
+		{dataloader.ErrCircuitOpen, codes.Unavailable, "circuit open"},
+		{&dataloader.PanicError{Value: "oops"}, codes.Internal, "internal error"},
+		{errors.New("connection refused"), codes.Internal, "internal error"},
+		{status.Error(codes.Unavailable, "upstream down"), codes.Unavailable, "upstream down"},
+	}
+
+	for _, tt := range tests {
+		s := grpcerr.Status(tt.err)
+		assert.Equal(t, tt.code, s.Code(), tt.err.Error())
+		assert.Equal(t, tt.msg, s.Message(), tt.err.Error())
+	}
+
+	s := grpcerr.Status(notFound)
+	if assert.Len(t, s.Details(), 1) {
+		info := s.Details()[0].(*errdetails.ErrorInfo)
+		assert.Equal(t, "NOT_FOUND", info.Reason)
+		assert.Equal(t, grpcerr.Domain, info.Domain)
+		assert.Equal(t, map[string]string{"key": "urn:isbn:fake-book"}, info.Metadata)
+	}
+
+	assert.NoError(t, grpcerr.Error(nil))
+}
@@ -0,0 +1,107 @@
+package dataloader
+
+import (
+	"context"
+	"sync"
+)
+
+// HashFetcher is like Fetcher, but for keys that are not comparable. Since
+// those keys cannot be used in a map, the returned map is keyed by the hash of
+// each key, as computed by the hash function given to NewHashed.
+type HashFetcher[K any, V any] func([]K) (map[string]V, error)
+
+// HashLoader is a Loader for keys that are not comparable, like slices, maps,
+// or structs that contain them. Keys are batched and deduplicated by a string
+// hash, so two keys with the same hash are treated as the same key.
+type HashLoader[K any, V any] struct {
+	mu      sync.Mutex
+	keys    map[string]*hashedKey[K]
+	hash    func(K) string
+	fetcher HashFetcher[K, V]
+	loader  *Loader[string, V]
+}
+
+// hashedKey tracks the original value of a key for as long as any caller is
+// waiting on it, so that it can be passed to the fetcher.
+type hashedKey[K any] struct {
+	key  K
+	refs int
+}
+
+// NewHashed creates a HashLoader. The hash function must return the same
+// string for keys that should be considered equal, and different strings
+// otherwise. Any options that take a key, like WithKeyFunc, operate on the
+// hashed keys.
+func NewHashed[K any, V any](fetchFn HashFetcher[K, V], hash func(K) string, opts ...Option) *HashLoader[K, V] {
+	h := &HashLoader[K, V]{
+		keys:    make(map[string]*hashedKey[K]),
+		hash:    hash,
+		fetcher: fetchFn,
+	}
+	h.loader = New(h.fetch, opts...)
+	return h
+}
+
+func (h *HashLoader[K, V]) Load(key K) (V, error) {
+	return h.LoadContext(context.Background(), key)
+}
+
+// LoadContext is like Load, but stops waiting when ctx is done, as with
+// Loader.LoadContext.
+func (h *HashLoader[K, V]) LoadContext(ctx context.Context, key K) (V, error) {
+	s := h.hash(key)
+	// the inner loader fetches normalized hashes, so index the key by those
+	nk := h.loader.keyFn(s)
+	h.acquire(nk, key)
+	defer h.release(nk)
+
+	v, err := h.loader.LoadContext(ctx, s)
+	if kErr, ok := err.(*keyError[string]); ok {
+		err = &keyError[K]{
+			err: kErr.err,
+			key: key,
+		}
+	}
+	return v, err
+}
+
+func (h *HashLoader[K, V]) LoadMany(keys ...K) ([]V, []error) {
+	return loadMany(keys, h.Load)
+}
+
+func (h *HashLoader[K, V]) acquire(s string, key K) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	hk, ok := h.keys[s]
+	if !ok {
+		hk = &hashedKey[K]{key: key}
+		h.keys[s] = hk
+	}
+	hk.refs++
+}
+
+func (h *HashLoader[K, V]) release(s string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	hk := h.keys[s]
+	hk.refs--
+	if hk.refs == 0 {
+		delete(h.keys, s)
+	}
+}
+
+func (h *HashLoader[K, V]) fetch(hashes []string) (map[string]V, error) {
+	h.mu.Lock()
+	keys := make([]K, 0, len(hashes))
+	for _, s := range hashes {
+		// keys whose callers have all gone away are not fetched
+		if hk, ok := h.keys[s]; ok {
+			keys = append(keys, hk.key)
+		}
+	}
+	h.mu.Unlock()
+
+	return h.fetcher(keys)
+}
@@ -0,0 +1,36 @@
+package dataloader
+
+import "time"
+
+// HedgePolicy controls hedged fetches, set by WithHedging. If a fetch has not
+// returned after a while, the same keys are fetched again, and whichever
+// fetch returns first is used. The context of the other one is cancelled, so
+// use a fetcher that accepts a context, as with NewContext.
+//
+// Hedging suits replicated upstreams with long-tail latency, where a second
+// request is likely to go to a faster replica. Hedged fetches count against
+// WithRateLimit, and are skipped if the limit has been reached.
+type HedgePolicy struct {
+	// Percentile, if set, hedges fetches that are slower than this
+	// fraction, like 0.95, of recent fetches. Until there have been enough
+	// fetches to tell, After is used.
+	Percentile float64
+
+	// After hedges fetches that are slower than this. Zero means fetches
+	// are only hedged by Percentile.
+	After time.Duration
+}
+
+// delay returns how long to wait before hedging a fetch, or 0 if it should
+// not be hedged.
+func (p *HedgePolicy) delay(l *latency) time.Duration {
+	if p == nil {
+		return 0
+	}
+	if p.Percentile > 0 {
+		if d, ok := l.percentile(p.Percentile); ok && d > 0 {
+			return d
+		}
+	}
+	return p.After
+}
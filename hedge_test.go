@@ -0,0 +1,72 @@
+package dataloader_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jsocol/dataloader"
+)
+
+// slowReplica returns a fetcher whose calls numbered in slow hang until they
+// are cancelled, as if they went to a slow replica, and that counts calls and
+// cancellations.
+func slowReplica(slow map[int64]bool, calls, cancelled *atomic.Int64) dataloader.ContextFetcher[string, int] {
+	return func(ctx context.Context, keys []string) (map[string]int, error) {
+		if slow[calls.Add(1)] {
+			<-ctx.Done()
+			cancelled.Add(1)
+			return nil, ctx.Err()
+		}
+		ret := make(map[string]int, len(keys))
+		for _, k := range keys {
+			ret[k] = len(k)
+		}
+		return ret, nil
+	}
+}
+
+func TestHedgeAfter(t *testing.T) {
+	var calls, cancelled atomic.Int64
+	fetcher := slowReplica(map[int64]bool{1: true}, &calls, &cancelled)
+
+	l := dataloader.NewContext(fetcher, dataloader.WithHedging(dataloader.HedgePolicy{
+		After: 20 * time.Millisecond,
+	}))
+
+	start := time.Now()
+	v, err := l.Load("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, v)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, int64(2), calls.Load())
+
+	// the slow call is cancelled
+	assert.Eventually(t, func() bool {
+		return cancelled.Load() == 1
+	}, time.Second, time.Millisecond)
+}
+
+func TestHedgePercentile(t *testing.T) {
+	var calls, cancelled atomic.Int64
+	fetcher := slowReplica(map[int64]bool{25: true}, &calls, &cancelled)
+
+	l := dataloader.NewContext(fetcher, dataloader.WithHedging(dataloader.HedgePolicy{
+		Percentile: 0.9,
+	}))
+
+	// no hedging until there are enough fetches to take the percentile from
+	for i := 0; i < 24; i++ {
+		_, err := l.Load("foo")
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int64(24), calls.Load())
+
+	v, err := l.Load("quux")
+	assert.NoError(t, err)
+	assert.Equal(t, 4, v)
+	assert.Equal(t, int64(26), calls.Load())
+}
@@ -0,0 +1,74 @@
+// Package httperr maps errors from loaders to HTTP responses.
+package httperr
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/jsocol/dataloader"
+	"github.com/jsocol/dataloader/internal/errclass"
+)
+
+// StatusClientClosedRequest is the non-standard status, used by nginx and
+// others, for requests the client cancelled before they completed.
+const StatusClientClosedRequest = 499
+
+// Code returns the HTTP status for err:
+//
+//   - 404 Not Found, for keys that were not found
+//   - 504 Gateway Timeout, for timeouts
+//   - 499 Client Closed Request, for cancellations
+//   - 503 Service Unavailable, for dataloader.ErrOverloaded and
+//     dataloader.ErrCircuitOpen
+//   - 500 Internal Server Error, for panics and any other error
+func Code(err error) int {
+	switch errclass.Of(err) {
+	case errclass.NotFound:
+		return http.StatusNotFound
+	case errclass.Timeout:
+		return http.StatusGatewayTimeout
+	case errclass.Canceled:
+		return StatusClientClosedRequest
+	case errclass.Overloaded, errclass.Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Body is the JSON body written by Write.
+type Body struct {
+	Error BodyError `json:"error"`
+}
+
+type BodyError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Key     string `json:"key,omitempty"`
+}
+
+// Write writes err to w with the status from Code, and a JSON Body that
+// includes the failing key, if there is one. The message is only written if
+// errclass.Exposed allows it.
+func Write(w http.ResponseWriter, err error) {
+	class := errclass.Of(err)
+
+	body := Body{
+		Error: BodyError{
+			Code:    class.String(),
+			Message: "internal error",
+		},
+	}
+	if class.Exposed() {
+		body.Error.Message = err.Error()
+	}
+	if key, ok := dataloader.ErrorKey(err); ok {
+		body.Error.Key = fmt.Sprint(key)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(Code(err))
+	_ = json.NewEncoder(w).Encode(body)
+}
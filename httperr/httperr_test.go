@@ -0,0 +1,74 @@
+package httperr_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jsocol/dataloader"
+	"github.com/jsocol/dataloader/httperr"
+)
+
+func TestCode(t *testing.T) {
+	l := dataloader.New(func(keys []string) (map[string]string, error) {
+		return nil, nil
+	})
+	_, notFound := l.Load("urn:isbn:fake-book")
+
+	tests := []struct {
+		err  error
+		code int
+	}{
+		{notFound, http.StatusNotFound},
+		{context.DeadlineExceeded, http.StatusGatewayTimeout},
+		{&dataloader.TimeoutError{Op: "fetch", After: time.Second}, http.StatusGatewayTimeout},
+		{context.Canceled, httperr.StatusClientClosedRequest},
+		{dataloader.ErrOverloaded, http.StatusServiceUnavailable},
+		{dataloader.ErrCircuitOpen, http.StatusServiceUnavailable},
+		{&dataloader.PanicError{Value: "oops"}, http.StatusInternalServerError},
+		{errors.New("connection refused"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.code, httperr.Code(tt.err), tt.err.Error())
+	}
+}
+
+func TestWrite(t *testing.T) {
+	l := dataloader.New(func(keys []string) (map[string]string, error) {
+		return nil, dataloader.KeyErrors[string]{
+			"urn:isbn:broken": errors.New("shard 3 unavailable"),
+		}
+	})
+
+	_, err := l.Load("urn:isbn:fake-book")
+	w := httptest.NewRecorder()
+	httperr.Write(w, err)
+
+	var body httperr.Body
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&body))
+	assert.Equal(t, httperr.BodyError{
+		Code:    "NOT_FOUND",
+		Message: "not found (urn:isbn:fake-book)",
+		Key:     "urn:isbn:fake-book",
+	}, body.Error)
+
+	_, err = l.Load("urn:isbn:broken")
+	w = httptest.NewRecorder()
+	httperr.Write(w, err)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&body))
+	assert.Equal(t, httperr.BodyError{
+		Code:    "INTERNAL",
+		Message: "internal error",
+		Key:     "urn:isbn:broken",
+	}, body.Error)
+}
@@ -0,0 +1,150 @@
+package httpfetch_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jsocol/dataloader"
+	"github.com/jsocol/dataloader/httpfetch"
+)
+
+func TestFetchEntries(t *testing.T) {
+	var full, notModified atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("id") == "2" {
+			w.Header().Set("Cache-Control", "no-store")
+		} else {
+			w.Header().Set("Cache-Control", "public, max-age=60")
+			w.Header().Set("ETag", `"v1"`)
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				notModified.Add(1)
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+
+		full.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		assert.NoError(t, json.NewEncoder(w).Encode([]person{{ID: 1, Name: "person 1"}, {ID: 2, Name: "person 2"}}))
+	}))
+	defer srv.Close()
+
+	f := httpfetch.New(srv.URL+"/people", personID)
+
+	got, err := f.FetchEntries([]int{1})
+	assert.NoError(t, err)
+	assert.Equal(t, dataloader.Entry[person]{Value: person{ID: 1, Name: "person 1"}, TTL: time.Minute}, got[1])
+
+	got, err = f.FetchEntries([]int{2})
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(-1), got[2].TTL)
+
+	// the same request again is revalidated instead of transferred
+	got, err = f.FetchEntries([]int{1})
+	assert.NoError(t, err)
+	assert.Equal(t, dataloader.Entry[person]{Value: person{ID: 1, Name: "person 1"}, TTL: time.Minute}, got[1])
+
+	assert.Equal(t, int64(2), full.Load())
+	assert.Equal(t, int64(1), notModified.Load())
+}
+
+func TestFetchEntriesRevalidateBatch(t *testing.T) {
+	var full, notModified atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		etag := `"` + r.URL.RawQuery + `"`
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		full.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		assert.NoError(t, json.NewEncoder(w).Encode([]person{{ID: 1, Name: "person 1"}, {ID: 2, Name: "person 2"}, {ID: 3, Name: "person 3"}}))
+	}))
+	defer srv.Close()
+
+	f := httpfetch.New(srv.URL+"/people", personID)
+
+	got, err := f.FetchEntries([]int{3, 1, 2})
+	assert.NoError(t, err)
+	assert.Len(t, got, 3)
+
+	// the same keys in another order make the same request
+	got, err = f.FetchEntries([]int{2, 3, 1})
+	assert.NoError(t, err)
+	assert.Len(t, got, 3)
+
+	assert.Equal(t, int64(1), full.Load())
+	assert.Equal(t, int64(1), notModified.Load())
+}
+
+func TestFetchEntriesMaxValidatorSize(t *testing.T) {
+	var full atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		full.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		assert.NoError(t, json.NewEncoder(w).Encode([]person{{ID: 1, Name: "person 1"}}))
+	}))
+	defer srv.Close()
+
+	// the response is too big to remember, so it is never revalidated
+	f := httpfetch.New(srv.URL+"/people", personID, httpfetch.WithMaxValidatorSize(8))
+	for range 2 {
+		got, err := f.FetchEntries([]int{1})
+		assert.NoError(t, err)
+		assert.Equal(t, "person 1", got[1].Value.Name)
+	}
+	assert.Equal(t, int64(2), full.Load())
+}
+
+func TestFetchEntriesLoader(t *testing.T) {
+	srv, rec := peopleServer(t)
+
+	f := httpfetch.New(srv.URL+"/people", personID)
+	l := dataloader.NewCache(f.FetchEntries, dataloader.WithCache(time.Hour))
+
+	for range 3 {
+		p, err := l.Load(1)
+		assert.NoError(t, err)
+		assert.Equal(t, "person 1", p.Name)
+	}
+
+	assert.Len(t, rec.Requests(), 1)
+}
+
+func TestFetchEntriesContext(t *testing.T) {
+	cancelled := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		close(cancelled)
+	}))
+	defer srv.Close()
+
+	f := httpfetch.New(srv.URL+"/people", personID)
+	l := dataloader.NewCacheContext(f.FetchEntriesContext, dataloader.WithFetchTimeout(10*time.Millisecond))
+
+	_, err := l.Load(1)
+	var tErr *dataloader.TimeoutError
+	assert.ErrorAs(t, err, &tErr)
+
+	// the request is cancelled, not left to run
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Error("request was not cancelled")
+	}
+}
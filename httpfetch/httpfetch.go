@@ -0,0 +1,482 @@
+// Package httpfetch builds dataloader fetchers for JSON REST endpoints that
+// take a list of IDs in the query string and return a JSON array.
+package httpfetch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jsocol/dataloader"
+)
+
+const (
+	// DefaultMaxURLLength is the longest URL a Fetcher will request when
+	// WithMaxURLLength is not used. It is a conservative limit that most
+	// servers and proxies accept.
+	DefaultMaxURLLength = 2048
+
+	// DefaultMaxBodySize is the most a Fetcher will read from a response when
+	// WithMaxBodySize is not used.
+	DefaultMaxBodySize = 4 << 20
+
+	// DefaultMaxValidators is the number of responses FetchEntries remembers
+	// for revalidation when WithMaxValidators is not used.
+	DefaultMaxValidators = 64
+
+	// DefaultMaxValidatorSize is the largest response body FetchEntries
+	// remembers for revalidation when WithMaxValidatorSize is not used.
+	DefaultMaxValidatorSize = 64 << 10
+)
+
+// Doer is implemented by *http.Client.
+type Doer interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+// StatusError is returned when the server responds with a non-2xx status. If
+// the status is 429 Too Many Requests or 503 Service Unavailable, it wraps
+// dataloader.ErrOverloaded.
+type StatusError struct {
+	StatusCode int
+	Status     string
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("httpfetch: unexpected status %s", e.Status)
+	}
+	return fmt.Sprintf("httpfetch: unexpected status %s: %s", e.Status, e.Body)
+}
+
+func (e *StatusError) Unwrap() error {
+	if e.StatusCode == http.StatusTooManyRequests || e.StatusCode == http.StatusServiceUnavailable {
+		return dataloader.ErrOverloaded
+	}
+	return nil
+}
+
+type config struct {
+	client       Doer
+	param        string
+	commas       bool
+	keyEncoder   any
+	maxURLLength int
+	maxBodySize  int64
+	post         bool
+
+	maxValidators    int
+	maxValidatorSize int64
+}
+
+type Option func(*config)
+
+// WithClient sets the client used to make requests. The default is
+// http.DefaultClient.
+func WithClient(client Doer) Option {
+	return func(c *config) {
+		c.client = client
+	}
+}
+
+// WithParam sets the name of the query parameter for IDs. The default is "id".
+func WithParam(name string) Option {
+	return func(c *config) {
+		c.param = name
+	}
+}
+
+// WithCommaSeparated sends IDs as a single comma-separated parameter, like
+// ?id=1,2,3, instead of repeating the parameter, like ?id=1&id=2&id=3.
+func WithCommaSeparated() Option {
+	return func(c *config) {
+		c.commas = true
+	}
+}
+
+// WithKeyEncoder sets how keys are written in the query string. The default
+// formats keys with fmt.Sprint. The type of fn must match the key type of the
+// Fetcher, or New will panic.
+func WithKeyEncoder[K comparable](fn func(K) string) Option {
+	return func(c *config) {
+		c.keyEncoder = fn
+	}
+}
+
+// WithMaxURLLength sets the longest URL the Fetcher will request. Batches that
+// would need a longer URL are split into several requests, unless
+// WithPostFallback is used.
+func WithMaxURLLength(n int) Option {
+	return func(c *config) {
+		c.maxURLLength = n
+	}
+}
+
+// WithPostFallback sends batches that would exceed the maximum URL length as a
+// single POST request, with the IDs form-encoded in the body, instead of
+// splitting them. The server must accept both.
+func WithPostFallback() Option {
+	return func(c *config) {
+		c.post = true
+	}
+}
+
+// WithMaxBodySize sets the most the Fetcher will read from a response.
+func WithMaxBodySize(n int64) Option {
+	return func(c *config) {
+		c.maxBodySize = n
+	}
+}
+
+// WithMaxValidators sets how many responses FetchEntries remembers, by URL, so
+// that it can revalidate them with If-None-Match. Zero turns revalidation off.
+func WithMaxValidators(n int) Option {
+	return func(c *config) {
+		c.maxValidators = n
+	}
+}
+
+// WithMaxValidatorSize sets the largest response body, in bytes, that
+// FetchEntries remembers for revalidation. Larger responses are not
+// revalidated, so that remembered responses take at most about n times the
+// number set by WithMaxValidators.
+func WithMaxValidatorSize(n int64) Option {
+	return func(c *config) {
+		c.maxValidatorSize = n
+	}
+}
+
+// Fetcher requests values by ID from a JSON REST endpoint. Its Fetch method
+// can be passed to dataloader.New, its FetchContext method to
+// dataloader.NewContext, its FetchEntries method to dataloader.NewCache, and
+// its FetchEntriesContext method to dataloader.NewCacheContext.
+type Fetcher[K comparable, V any] struct {
+	baseURL *url.URL
+	keyOf   func(V) K
+	encode  func(K) string
+	config  config
+
+	mu         sync.Mutex
+	validators map[string]validator[V]
+}
+
+// validator is a response body that can be reused if the server responds to
+// a conditional request with 304 Not Modified.
+type validator[V any] struct {
+	etag   string
+	values []V
+}
+
+// New creates a Fetcher for the endpoint at baseURL. The endpoint must respond
+// with a JSON array of values, and keyOf is called on each value to find its
+// key. IDs that are missing from the response are not found.
+func New[K comparable, V any](baseURL string, keyOf func(V) K, opts ...Option) *Fetcher[K, V] {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		panic(fmt.Sprintf("httpfetch: invalid URL %q: %v", baseURL, err))
+	}
+
+	c := config{
+		client:           http.DefaultClient,
+		param:            "id",
+		maxURLLength:     DefaultMaxURLLength,
+		maxBodySize:      DefaultMaxBodySize,
+		maxValidators:    DefaultMaxValidators,
+		maxValidatorSize: DefaultMaxValidatorSize,
+	}
+	for _, o := range opts {
+		o(&c)
+	}
+
+	encode := func(k K) string {
+		return fmt.Sprint(k)
+	}
+	if c.keyEncoder != nil {
+		fn, ok := c.keyEncoder.(func(K) string)
+		if !ok {
+			var k K
+			panic(fmt.Sprintf("httpfetch: WithKeyEncoder must be given a func(%T) string", k))
+		}
+		encode = fn
+	}
+
+	return &Fetcher[K, V]{
+		baseURL:    u,
+		keyOf:      keyOf,
+		encode:     encode,
+		config:     c,
+		validators: make(map[string]validator[V]),
+	}
+}
+
+// Fetch requests the values for keys, splitting them into as many requests as
+// needed to stay under the maximum URL length.
+func (f *Fetcher[K, V]) Fetch(keys []K) (map[K]V, error) {
+	return f.FetchContext(context.Background(), keys)
+}
+
+// FetchContext is like Fetch, but makes the requests with ctx, so that they are
+// cancelled along with the batch.
+func (f *Fetcher[K, V]) FetchContext(ctx context.Context, keys []K) (map[K]V, error) {
+	ret := make(map[K]V, len(keys))
+	err := f.fetch(ctx, keys, false, func(v V, _ time.Duration) {
+		ret[f.keyOf(v)] = v
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// FetchEntries is like Fetch, but sets the TTL of each value from the
+// Cache-Control header of its response: max-age is the TTL, and no-store or
+// no-cache mean the value is not cached.
+//
+// Small responses with an ETag are remembered, and requested again with
+// If-None-Match, so that a 304 Not Modified refreshes their TTLs without
+// transferring the body again. An ETag belongs to a whole response, so only a
+// request for exactly the same IDs can be revalidated. That suits batches that
+// are loaded together over and over, like a fixed list of settings, but keys
+// that expire and are batched with different neighbours are fetched in full.
+func (f *Fetcher[K, V]) FetchEntries(keys []K) (map[K]dataloader.Entry[V], error) {
+	return f.FetchEntriesContext(context.Background(), keys)
+}
+
+// FetchEntriesContext is like FetchEntries, but makes the requests with ctx,
+// so that they are cancelled along with the batch.
+func (f *Fetcher[K, V]) FetchEntriesContext(ctx context.Context, keys []K) (map[K]dataloader.Entry[V], error) {
+	ret := make(map[K]dataloader.Entry[V], len(keys))
+	err := f.fetch(ctx, keys, true, func(v V, ttl time.Duration) {
+		ret[f.keyOf(v)] = dataloader.Entry[V]{
+			Value: v,
+			TTL:   ttl,
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// fetch calls add with each value in the responses for keys.
+func (f *Fetcher[K, V]) fetch(ctx context.Context, keys []K, revalidate bool, add func(V, time.Duration)) error {
+	for _, ids := range f.split(keys) {
+		values, ttl, err := f.request(ctx, ids, revalidate)
+		if err != nil {
+			return err
+		}
+		for _, v := range values {
+			add(v, ttl)
+		}
+	}
+	return nil
+}
+
+// split encodes keys into groups of IDs that each fit in a URL. With the POST
+// fallback, there is only ever one group. The IDs are sorted, so that the same
+// keys always make the same URLs, whatever order the loader gives them in.
+func (f *Fetcher[K, V]) split(keys []K) [][]string {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	encoded := make([]string, len(keys))
+	for i, k := range keys {
+		encoded[i] = f.encode(k)
+	}
+	slices.Sort(encoded)
+
+	// the base URL, plus "?" or "&" before the IDs
+	base := len(f.baseURL.String()) + 1
+
+	var ret [][]string
+	var ids []string
+	length := base
+	for _, id := range encoded {
+		// the length of "id=" or "&id=" or "%2C" plus the escaped ID
+		n := len(url.QueryEscape(id))
+		switch {
+		case len(ids) == 0:
+			n += len(f.config.param) + 1
+		case f.config.commas:
+			n += 3
+		default:
+			n += len(f.config.param) + 2
+		}
+
+		if len(ids) > 0 && length+n > f.config.maxURLLength && !f.config.post {
+			ret = append(ret, ids)
+			ids = nil
+			length = base
+			n = len(url.QueryEscape(id)) + len(f.config.param) + 1
+		}
+		ids = append(ids, id)
+		length += n
+	}
+	return append(ret, ids)
+}
+
+func (f *Fetcher[K, V]) request(ctx context.Context, ids []string, revalidate bool) ([]V, time.Duration, error) {
+	qv := url.Values{}
+	if f.config.commas {
+		qv.Set(f.config.param, strings.Join(ids, ","))
+	} else {
+		qv[f.config.param] = ids
+	}
+	params := qv.Encode()
+
+	u := *f.baseURL
+	if u.RawQuery != "" {
+		params = u.RawQuery + "&" + params
+	}
+
+	var req *http.Request
+	var err error
+	if f.config.post && len(u.String())+len(params)+1 > f.config.maxURLLength {
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, u.String(), strings.NewReader(params))
+		if err != nil {
+			return nil, 0, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		// conditional requests only make sense for GET
+		revalidate = false
+	} else {
+		u.RawQuery = params
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	req.Header.Set("Accept", "application/json")
+
+	var stale validator[V]
+	if revalidate {
+		f.mu.Lock()
+		stale = f.validators[req.URL.String()]
+		f.mu.Unlock()
+		if stale.etag != "" {
+			req.Header.Set("If-None-Match", stale.etag)
+		}
+	}
+
+	resp, err := f.config.client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	ttl := maxAge(resp.Header)
+
+	if resp.StatusCode == http.StatusNotModified && stale.etag != "" {
+		return stale.values, ttl, nil
+	}
+
+	body := io.LimitReader(resp.Body, f.config.maxBodySize)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		// include the start of the body, which often explains the error
+		msg, _ := io.ReadAll(io.LimitReader(body, 512))
+		return nil, 0, &StatusError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Body:       strings.TrimSpace(string(msg)),
+		}
+	}
+
+	counted := &countingReader{r: body}
+	values := make([]V, 0, len(ids))
+	if err = json.NewDecoder(counted).Decode(&values); err != nil {
+		return nil, 0, err
+	}
+
+	if revalidate {
+		f.remember(req.URL.String(), resp.Header, values, counted.n)
+	}
+
+	return values, ttl, nil
+}
+
+// remember stores the response for rawURL if it can be revalidated, and is
+// no larger than the maximum size.
+func (f *Fetcher[K, V]) remember(rawURL string, h http.Header, values []V, size int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	etag := h.Get("ETag")
+	if etag == "" || hasDirective(h, "no-store") || size > f.config.maxValidatorSize {
+		delete(f.validators, rawURL)
+		return
+	}
+
+	if _, ok := f.validators[rawURL]; !ok && len(f.validators) >= f.config.maxValidators {
+		// make room by forgetting an arbitrary response
+		for k := range f.validators {
+			delete(f.validators, k)
+			break
+		}
+	}
+	if f.config.maxValidators > 0 {
+		f.validators[rawURL] = validator[V]{etag: etag, values: values}
+	}
+}
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// maxAge is the TTL of a response, from its Cache-Control and Age headers. It
+// is negative if the response must not be cached, and zero if the response
+// does not say.
+func maxAge(h http.Header) time.Duration {
+	if hasDirective(h, "no-store") || hasDirective(h, "no-cache") {
+		return -1
+	}
+
+	for _, d := range directives(h) {
+		v, ok := strings.CutPrefix(d, "max-age=")
+		if !ok {
+			continue
+		}
+		secs, err := strconv.Atoi(v)
+		if err != nil {
+			return 0
+		}
+		age, _ := strconv.Atoi(h.Get("Age"))
+		if secs <= age {
+			return -1
+		}
+		return time.Duration(secs-age) * time.Second
+	}
+	return 0
+}
+
+func hasDirective(h http.Header, name string) bool {
+	return slices.Contains(directives(h), name)
+}
+
+func directives(h http.Header) []string {
+	var ret []string
+	for _, v := range h.Values("Cache-Control") {
+		for _, d := range strings.Split(v, ",") {
+			ret = append(ret, strings.ToLower(strings.TrimSpace(d)))
+		}
+	}
+	return ret
+}
@@ -0,0 +1,203 @@
+package httpfetch_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jsocol/dataloader"
+	"github.com/jsocol/dataloader/httpfetch"
+)
+
+type person struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func personID(p person) int {
+	return p.ID
+}
+
+type recorder struct {
+	mu       sync.Mutex
+	requests []*http.Request
+}
+
+func (rec *recorder) Requests() []*http.Request {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return append([]*http.Request(nil), rec.requests...)
+}
+
+// peopleServer serves people 1 through 9 from GET or POST requests, with
+// repeated or comma-separated IDs.
+func peopleServer(t *testing.T) (*httptest.Server, *recorder) {
+	rec := &recorder{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, r.ParseForm())
+
+		rec.mu.Lock()
+		rec.requests = append(rec.requests, r)
+		rec.mu.Unlock()
+
+		var ret []person
+		for _, param := range r.Form["id"] {
+			for _, id := range strings.Split(param, ",") {
+				n, err := strconv.Atoi(id)
+				if err == nil && n > 0 && n < 10 {
+					ret = append(ret, person{ID: n, Name: "person " + id})
+				}
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		assert.NoError(t, json.NewEncoder(w).Encode(ret))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, rec
+}
+
+func TestFetch(t *testing.T) {
+	srv, rec := peopleServer(t)
+
+	f := httpfetch.New(srv.URL+"/people", personID)
+	got, err := f.Fetch([]int{1, 2, 42})
+	assert.NoError(t, err)
+	assert.Equal(t, map[int]person{
+		1: {ID: 1, Name: "person 1"},
+		2: {ID: 2, Name: "person 2"},
+	}, got)
+
+	reqs := rec.Requests()
+	assert.Len(t, reqs, 1)
+	assert.Equal(t, http.MethodGet, reqs[0].Method)
+	assert.Equal(t, "/people?id=1&id=2&id=42", reqs[0].RequestURI)
+}
+
+func TestFetchKeyFunc(t *testing.T) {
+	type user struct {
+		Login string `json:"login"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		assert.NoError(t, json.NewEncoder(w).Encode([]user{{Login: "JSocol"}, {Login: "never-asked"}}))
+	}))
+	defer srv.Close()
+
+	f := httpfetch.New(srv.URL+"/users", func(u user) string { return u.Login })
+
+	// the loader matches the server's spelling of the key, and ignores values
+	// no one asked for
+	l := dataloader.New(f.Fetch, dataloader.WithKeyFunc(strings.ToLower))
+	u, err := l.Load("jsocol")
+	assert.NoError(t, err)
+	assert.Equal(t, "JSocol", u.Login)
+}
+
+func TestFetchContext(t *testing.T) {
+	srv, rec := peopleServer(t)
+
+	f := httpfetch.New(srv.URL+"/people", personID)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := f.FetchContext(ctx, []int{1})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, rec.Requests())
+}
+
+func TestFetchNoKeys(t *testing.T) {
+	srv, rec := peopleServer(t)
+
+	f := httpfetch.New(srv.URL+"/people", personID)
+	got, err := f.Fetch(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, got)
+	assert.Empty(t, rec.Requests())
+}
+
+func TestFetchCommaSeparated(t *testing.T) {
+	srv, rec := peopleServer(t)
+
+	f := httpfetch.New(srv.URL+"/people", personID,
+		httpfetch.WithCommaSeparated(),
+		httpfetch.WithParam("id"),
+		httpfetch.WithKeyEncoder(func(id int) string { return "0" + strconv.Itoa(id) }),
+	)
+	got, err := f.Fetch([]int{3, 4})
+	assert.NoError(t, err)
+	assert.Len(t, got, 2)
+
+	reqs := rec.Requests()
+	assert.Len(t, reqs, 1)
+	assert.Equal(t, "/people?id=03%2C04", reqs[0].RequestURI)
+}
+
+func TestFetchSplitsLongURLs(t *testing.T) {
+	srv, rec := peopleServer(t)
+
+	base := srv.URL + "/people"
+	// room for exactly three IDs: "?id=1&id=2&id=3"
+	f := httpfetch.New(base, personID, httpfetch.WithMaxURLLength(len(base)+15))
+	got, err := f.Fetch([]int{1, 2, 3, 4, 5, 6, 7})
+	assert.NoError(t, err)
+	assert.Len(t, got, 7)
+
+	var uris []string
+	for _, r := range rec.Requests() {
+		assert.LessOrEqual(t, len(srv.URL)+len(r.RequestURI), len(base)+15)
+		uris = append(uris, r.RequestURI)
+	}
+	assert.Equal(t, []string{
+		"/people?id=1&id=2&id=3",
+		"/people?id=4&id=5&id=6",
+		"/people?id=7",
+	}, uris)
+}
+
+func TestFetchPostFallback(t *testing.T) {
+	srv, rec := peopleServer(t)
+
+	base := srv.URL + "/people"
+	f := httpfetch.New(base, personID,
+		httpfetch.WithMaxURLLength(len(base)+15),
+		httpfetch.WithPostFallback(),
+	)
+
+	got, err := f.Fetch([]int{1, 2})
+	assert.NoError(t, err)
+	assert.Len(t, got, 2)
+
+	got, err = f.Fetch([]int{1, 2, 3, 4, 5, 6, 7})
+	assert.NoError(t, err)
+	assert.Len(t, got, 7)
+
+	reqs := rec.Requests()
+	assert.Len(t, reqs, 2)
+	assert.Equal(t, http.MethodGet, reqs[0].Method)
+	assert.Equal(t, http.MethodPost, reqs[1].Method)
+	assert.Equal(t, "/people", reqs[1].RequestURI)
+}
+
+func TestFetchStatusError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "upstream unavailable", http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	f := httpfetch.New(srv.URL+"/people", personID)
+	got, err := f.Fetch([]int{1})
+	assert.Nil(t, got)
+
+	var sErr *httpfetch.StatusError
+	assert.ErrorAs(t, err, &sErr)
+	assert.Equal(t, http.StatusBadGateway, sErr.StatusCode)
+	assert.EqualError(t, err, "httpfetch: unexpected status 502 Bad Gateway: upstream unavailable")
+}
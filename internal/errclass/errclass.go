@@ -0,0 +1,75 @@
+// Package errclass sorts errors from loaders into the few kinds that callers
+// handle differently, so that httperr, grpcerr and gqlerr agree.
+package errclass
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jsocol/dataloader"
+)
+
+type Class int
+
+const (
+	Internal Class = iota
+	NotFound
+	Timeout
+	Canceled
+	Overloaded
+	Panic
+	Unavailable
+)
+
+// String returns the class in the SCREAMING_SNAKE_CASE used for GraphQL error
+// codes and gRPC error reasons.
+func (c Class) String() string {
+	switch c {
+	case NotFound:
+		return "NOT_FOUND"
+	case Timeout:
+		return "TIMEOUT"
+	case Canceled:
+		return "CANCELED"
+	case Overloaded:
+		return "OVERLOADED"
+	case Panic:
+		return "PANIC"
+	case Unavailable:
+		return "UNAVAILABLE"
+	default:
+		return "INTERNAL"
+	}
+}
+
+// Exposed reports whether the message of an error of this class is safe to
+// show to clients. Internal errors and panics may contain details of the
+// upstream, so they are replaced with a generic message.
+func (c Class) Exposed() bool {
+	return c != Internal && c != Panic
+}
+
+// Of classifies err.
+func Of(err error) Class {
+	var panicErr *dataloader.PanicError
+	var timeout interface{ Timeout() bool }
+
+	switch {
+	case errors.Is(err, dataloader.ErrNotFound):
+		return NotFound
+	case errors.As(err, &panicErr):
+		return Panic
+	case errors.Is(err, dataloader.ErrCircuitOpen):
+		return Unavailable
+	case errors.Is(err, dataloader.ErrOverloaded):
+		return Overloaded
+	case errors.Is(err, context.Canceled):
+		return Canceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return Timeout
+	case errors.As(err, &timeout) && timeout.Timeout():
+		return Timeout
+	default:
+		return Internal
+	}
+}
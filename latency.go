@@ -0,0 +1,66 @@
+package dataloader
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// latencyAlpha is the weight of each new fetch in the latency estimate.
+	latencyAlpha = 0.2
+
+	// latencySamples is how many recent fetches latency percentiles are
+	// taken from.
+	latencySamples = 128
+
+	// minLatencySamples is the fewest fetches a percentile is taken from.
+	minLatencySamples = 20
+)
+
+// latency tracks how long the fetcher takes, as an exponentially weighted
+// moving average of recent fetches, and a ring of the most recent ones.
+type latency struct {
+	mu      sync.Mutex
+	ewma    time.Duration
+	samples [latencySamples]time.Duration
+	n       int
+}
+
+func (e *latency) observe(d time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.n == 0 {
+		e.ewma = d
+	} else {
+		e.ewma += time.Duration(latencyAlpha * float64(d-e.ewma))
+	}
+	e.samples[e.n%latencySamples] = d
+	e.n++
+}
+
+// estimate returns the expected fetch latency. It returns false before the
+// first fetch, when there is nothing to expect.
+func (e *latency) estimate() (time.Duration, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.ewma, e.n > 0
+}
+
+// percentile returns the latency that the fraction p of recent fetches took
+// at most. It returns false if there have not been enough fetches yet.
+func (e *latency) percentile(p float64) (time.Duration, bool) {
+	e.mu.Lock()
+	n := min(e.n, latencySamples)
+	if n < minLatencySamples {
+		e.mu.Unlock()
+		return 0, false
+	}
+	samples := slices.Clone(e.samples[:n])
+	e.mu.Unlock()
+
+	slices.Sort(samples)
+	i := int(p * float64(n))
+	return samples[min(max(i, 0), n-1)], true
+}
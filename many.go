@@ -0,0 +1,42 @@
+package dataloader
+
+import (
+	"context"
+	"errors"
+)
+
+// ManyFetcher is like Fetcher, but for one-to-many relationships, like books by
+// author ID. Each key maps to every value for that key. Keys with no values
+// may be left out of the returned map.
+type ManyFetcher[K comparable, V any] func([]K) (map[K][]V, error)
+
+// ManyLoader loads every value for a key, batching keys the same way as
+// Loader. Unlike Loader, a key with no values is not an error: it loads as an
+// empty slice.
+type ManyLoader[K comparable, V any] struct {
+	loader *Loader[K, []V]
+}
+
+func NewMany[K comparable, V any](fetchFn ManyFetcher[K, V], opts ...Option) *ManyLoader[K, V] {
+	return &ManyLoader[K, V]{
+		loader: New(Fetcher[K, []V](fetchFn), opts...),
+	}
+}
+
+func (m *ManyLoader[K, V]) Load(key K) ([]V, error) {
+	return m.LoadContext(context.Background(), key)
+}
+
+// LoadContext is like Load, but stops waiting when ctx is done, as with
+// Loader.LoadContext.
+func (m *ManyLoader[K, V]) LoadContext(ctx context.Context, key K) ([]V, error) {
+	vs, err := m.loader.LoadContext(ctx, key)
+	if errors.Is(err, ErrNotFound) {
+		return []V{}, nil
+	}
+	return vs, err
+}
+
+func (m *ManyLoader[K, V]) LoadMany(keys ...K) ([][]V, []error) {
+	return loadMany(keys, m.Load)
+}
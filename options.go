@@ -15,3 +15,165 @@ func WithMaxBatch(batchSize int) Option {
 		c.maxBatch = batchSize
 	}
 }
+
+// WithKeyFunc normalizes keys before they are batched, so that different
+// spellings of the same logical key, like "URN:ISBN:978-1098118730" and
+// "urn:isbn:978-1098118730", are only fetched once. The fetcher receives the
+// normalized keys, and each result is sent to every caller whose key
+// normalizes to it. Errors still report the key the caller asked for.
+//
+// The type of fn must match the key type of the Loader, or New will panic.
+func WithKeyFunc[K comparable](fn func(K) K) Option {
+	return func(c *config) {
+		c.keyFunc = fn
+	}
+}
+
+// WithCostFunc sets the cost of each key, for WithMaxBatchCost, for example
+// to weigh keys whose responses are large, or that the upstream charges more
+// for. Costs are computed for normalized keys. Keys cost 1 by default.
+//
+// The type of fn must match the key type of the Loader, or New will panic.
+func WithCostFunc[K comparable](fn func(K) int) Option {
+	return func(c *config) {
+		c.costFunc = fn
+	}
+}
+
+// WithMaxBatchCost limits batches by the total cost of their keys, as set by
+// WithCostFunc, instead of by the number of keys. A batch is fetched as soon
+// as it reaches maxCost, and a key that would take it over maxCost starts a
+// new batch. A key that costs more than maxCost on its own is fetched alone.
+func WithMaxBatchCost(maxCost int) Option {
+	return func(c *config) {
+		c.maxCost = maxCost
+	}
+}
+
+// WithFallback fetches keys that the fetcher did not find, or failed to load,
+// from a second source, like an archive table or a legacy service. After any
+// retries, those keys are passed to fallback in one batch. That includes
+// batches that failed as a whole, even with ErrCircuitOpen. Only keys that
+// neither source finds are not found, and errors from fallback replace those
+// from the fetcher.
+//
+// The fallback is called like the fetcher: it is subject to WithFetchTimeout
+// and WithRateLimit, and a panic fails its keys. It is not hedged, and its
+// errors do not count toward the circuit breaker.
+//
+// The type of fallback must match the Loader, or New will panic.
+func WithFallback[K comparable, V any](fallback Fetcher[K, V]) Option {
+	return func(c *config) {
+		c.fallback = fallback
+	}
+}
+
+// WithFallbackContext is like WithFallback, for a fallback that accepts a
+// context. The context is cancelled if every caller waiting on the batch goes
+// away, or the fetch timeout passes.
+func WithFallbackContext[K comparable, V any](fallback ContextFetcher[K, V]) Option {
+	return func(c *config) {
+		c.fallback = fallback
+	}
+}
+
+// WithCache keeps fetched values for ttl, so that loading them again does not
+// call the fetcher. Loaders created with NewCache use ttl for values that do
+// not set their own.
+func WithCache(ttl time.Duration) Option {
+	return func(c *config) {
+		c.cache = true
+		c.ttl = ttl
+	}
+}
+
+// WithRetry retries keys that fail to load, as set by policy.
+func WithRetry(policy RetryPolicy) Option {
+	return func(c *config) {
+		c.retry = &policy
+	}
+}
+
+// WithCircuitBreaker stops sending batches to a failing fetcher, as set by
+// policy. While the breaker is open, loads fail with ErrCircuitOpen instead of
+// waiting on the fetcher.
+func WithCircuitBreaker(policy BreakerPolicy) Option {
+	return func(c *config) {
+		c.breaker = &policy
+	}
+}
+
+// WithFetchTimeout fails a batch with a *TimeoutError if the fetcher takes
+// longer than d. The fetcher is not waited for, but it keeps running unless it
+// accepts a context, as with NewContext, in which case the context is
+// cancelled.
+func WithFetchTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.fetchTimeout = d
+	}
+}
+
+// WithLoadTimeout bounds how long Load waits for a key, including the batch
+// delay and any retries, even if the fetcher hangs. Loads that time out return
+// a *TimeoutError.
+func WithLoadTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.loadTimeout = d
+	}
+}
+
+// WithRateLimit limits how often the fetcher is called, and how many keys it
+// is asked for, as set by limit.
+func WithRateLimit(limit RateLimit) Option {
+	return func(c *config) {
+		c.rate = &limit
+	}
+}
+
+// OverflowPolicy is what Load does when the limit set by WithMaxPending is
+// reached.
+type OverflowPolicy int
+
+const (
+	// Block waits until another Load finishes, or until the timeout set by
+	// WithLoadTimeout.
+	Block OverflowPolicy = iota
+	// FailFast returns ErrOverloaded right away, to shed load.
+	FailFast
+)
+
+// WithMaxPending limits how many Load calls, including each key of LoadMany,
+// may be waiting for a result at once. Once there are n, further calls block
+// or fail, as set by policy.
+func WithMaxPending(n int, policy OverflowPolicy) Option {
+	return func(c *config) {
+		c.maxPending = n
+		c.overflow = policy
+	}
+}
+
+// WithHighPriorityDelay sets how long PriorityHigh loads wait for other keys
+// to batch with. The default is 0, so they are fetched right away, along with
+// any keys already waiting.
+func WithHighPriorityDelay(delay time.Duration) Option {
+	return func(c *config) {
+		c.highDelay = delay
+	}
+}
+
+// WithLowPriorityDelay sets how long PriorityLow loads wait for room in other
+// batches before they are fetched in a batch of their own. The default is 10
+// times the delay set by WithDelay.
+func WithLowPriorityDelay(delay time.Duration) Option {
+	return func(c *config) {
+		c.lowDelay = delay
+	}
+}
+
+// WithHedging fetches slow batches again, and uses whichever fetch returns
+// first, as set by policy.
+func WithHedging(policy HedgePolicy) Option {
+	return func(c *config) {
+		c.hedge = &policy
+	}
+}
@@ -0,0 +1,34 @@
+package dataloader
+
+import "context"
+
+// Priority sets how urgently a load is fetched. Use WithPriority to set the
+// priority of loads made with a context.
+type Priority int
+
+const (
+	// PriorityNormal loads are batched as usual.
+	PriorityNormal Priority = iota
+
+	// PriorityHigh loads are fetched right away, with whatever keys are
+	// pending, or after the delay set by WithHighPriorityDelay.
+	PriorityHigh
+
+	// PriorityLow loads, like background prefetches, do not start batches
+	// of their own until the delay set by WithLowPriorityDelay. Until then,
+	// they only fill the room left in batches of other loads. If another
+	// load asks for the same key, it is fetched as usual.
+	PriorityLow
+)
+
+type priorityKey struct{}
+
+// WithPriority returns a context that makes LoadContext load with priority p.
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+func priorityOf(ctx context.Context) Priority {
+	p, _ := ctx.Value(priorityKey{}).(Priority)
+	return p
+}
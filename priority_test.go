@@ -0,0 +1,96 @@
+package dataloader_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jsocol/dataloader"
+)
+
+func TestHighPriority(t *testing.T) {
+	rec := &recorder{}
+	fetcher := rec.fetch
+
+	l := dataloader.New(fetcher, dataloader.WithDelay(200*time.Millisecond))
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		v, err := l.Load("normal")
+		assert.NoError(t, err)
+		assert.Equal(t, 6, v)
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	ctx := dataloader.WithPriority(context.Background(), dataloader.PriorityHigh)
+	v, err := l.LoadContext(ctx, "high")
+	assert.NoError(t, err)
+	assert.Equal(t, 4, v)
+	wg.Wait()
+
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+	assert.Equal(t, [][]string{{"high", "normal"}}, rec.Batches())
+}
+
+func TestLowPriority(t *testing.T) {
+	rec := &recorder{}
+	fetcher := rec.fetch
+
+	l := dataloader.New(fetcher,
+		dataloader.WithDelay(10*time.Millisecond),
+		dataloader.WithLowPriorityDelay(100*time.Millisecond),
+		dataloader.WithMaxBatch(2),
+	)
+
+	ctx := dataloader.WithPriority(context.Background(), dataloader.PriorityLow)
+	start := time.Now()
+	var wg sync.WaitGroup
+	wg.Add(2)
+	for _, k := range []string{"warm1", "warm2"} {
+		go func(k string) {
+			defer wg.Done()
+			v, err := l.LoadContext(ctx, k)
+			assert.NoError(t, err)
+			assert.Equal(t, 5, v)
+		}(k)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	// the low priority keys fill the room left in this batch, but do not
+	// delay it
+	v, err := l.Load("user")
+	assert.NoError(t, err)
+	assert.Equal(t, 4, v)
+	assert.Less(t, time.Since(start), 50*time.Millisecond)
+
+	wg.Wait()
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+
+	if batches := rec.Batches(); assert.Len(t, batches, 2) {
+		assert.Len(t, batches[0], 2)
+		assert.Contains(t, batches[0], "user")
+		assert.Len(t, batches[1], 1)
+	}
+
+	// a normal load of a low priority key does not wait for the low delay
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, err := l.LoadContext(ctx, "prefetched")
+		assert.NoError(t, err)
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	start = time.Now()
+	v, err = l.Load("prefetched")
+	assert.NoError(t, err)
+	assert.Equal(t, 10, v)
+	assert.Less(t, time.Since(start), 50*time.Millisecond)
+	<-done
+}
@@ -0,0 +1,135 @@
+package dataloader
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimit limits how often a Loader calls its fetcher, and how many keys it
+// fetches, set by WithRateLimit. Zero rates are not limited.
+//
+// When the limit for batches is reached, the next batch waits, and keys loaded
+// in the meantime are added to it. When the limit for keys is reached, only
+// as many keys as are allowed are fetched, and the rest wait for the next
+// batch. Retries count against both limits.
+type RateLimit struct {
+	// BatchesPerSecond is the most fetcher calls per second, on average.
+	BatchesPerSecond float64
+	// BatchBurst is the most fetcher calls that can be made at once after
+	// a quiet period. The default is one second's worth, or at least 1.
+	BatchBurst int
+
+	// KeysPerSecond is the most keys fetched per second, on average.
+	KeysPerSecond float64
+	// KeyBurst is the most keys that can be fetched at once after a quiet
+	// period. The default is one second's worth, or at least 1.
+	KeyBurst int
+}
+
+// tokenBucket is a token bucket that fills at rate tokens per second, up to
+// burst tokens.
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if rate <= 0 {
+		return nil
+	}
+	b := float64(burst)
+	if b <= 0 {
+		b = max(math.Ceil(rate), 1)
+	}
+	return &tokenBucket{
+		rate:   rate,
+		burst:  b,
+		tokens: b,
+		last:   time.Now(),
+	}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	b.tokens = min(b.tokens+now.Sub(b.last).Seconds()*b.rate, b.burst)
+	b.last = now
+}
+
+// wait returns how long until there is a whole token.
+func (b *tokenBucket) wait() time.Duration {
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// limiter enforces a RateLimit. A nil *limiter does not limit anything.
+type limiter struct {
+	mu      sync.Mutex
+	batches *tokenBucket
+	keys    *tokenBucket
+}
+
+func newLimiter(r RateLimit) *limiter {
+	l := &limiter{
+		batches: newTokenBucket(r.BatchesPerSecond, r.BatchBurst),
+		keys:    newTokenBucket(r.KeysPerSecond, r.KeyBurst),
+	}
+	if l.batches == nil && l.keys == nil {
+		return nil
+	}
+	return l
+}
+
+// reserve takes the tokens to fetch a batch of up to n keys, and returns how
+// many keys may be fetched. If there is not a token for the batch and one for
+// at least one key, it takes nothing and returns how long to wait instead.
+//
+// If all is true, it takes tokens for all n keys, even if that leaves the
+// bucket in debt, so that a retry is not split up.
+func (l *limiter) reserve(n int, all bool) (int, time.Duration) {
+	if l == nil {
+		return n, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	var wait time.Duration
+	if l.batches != nil {
+		l.batches.refill(now)
+		wait = l.batches.wait()
+	}
+	if l.keys != nil {
+		l.keys.refill(now)
+		wait = max(wait, l.keys.wait())
+	}
+	if wait > 0 {
+		return 0, wait
+	}
+
+	if l.batches != nil {
+		l.batches.tokens--
+	}
+	if l.keys != nil {
+		if !all {
+			n = min(n, int(l.keys.tokens))
+		}
+		l.keys.tokens -= float64(n)
+	}
+	return n, 0
+}
+
+// wait blocks until it can reserve tokens to fetch all n keys.
+func (l *limiter) wait(n int) {
+	for {
+		_, wait := l.reserve(n, true)
+		if wait == 0 {
+			return
+		}
+		time.Sleep(wait)
+	}
+}
@@ -0,0 +1,73 @@
+package dataloader_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jsocol/dataloader"
+)
+
+func TestRateLimitBatches(t *testing.T) {
+	rec := &recorder{}
+	l := dataloader.New(rec.fetch, dataloader.WithRateLimit(dataloader.RateLimit{
+		BatchesPerSecond: 20,
+		BatchBurst:       1,
+	}))
+
+	start := time.Now()
+	_, err := l.Load("a")
+	assert.NoError(t, err)
+
+	// these wait for the next batch token, and are fetched together
+	var wg sync.WaitGroup
+	for i, k := range []string{"b", "cc", "ddd"} {
+		wg.Add(1)
+		go func(k string) {
+			defer wg.Done()
+			v, err := l.Load(k)
+			assert.NoError(t, err)
+			assert.Equal(t, len(k), v)
+		}(k)
+		time.Sleep(time.Duration(i) * 5 * time.Millisecond)
+	}
+	wg.Wait()
+
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+	assert.Equal(t, [][]string{{"a"}, {"b", "cc", "ddd"}}, rec.Batches())
+}
+
+func TestRateLimitKeys(t *testing.T) {
+	var mu sync.Mutex
+	var batches []int
+	fetcher := func(keys []int) (map[int]int, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		batches = append(batches, len(keys))
+		ret := make(map[int]int, len(keys))
+		for _, k := range keys {
+			ret[k] = k * 2
+		}
+		return ret, nil
+	}
+
+	l := dataloader.New(fetcher, dataloader.WithRateLimit(dataloader.RateLimit{
+		KeysPerSecond: 100,
+		KeyBurst:      2,
+	}))
+
+	start := time.Now()
+	vals, errs := l.LoadMany(1, 2, 3, 4, 5)
+	assert.Empty(t, errs)
+	assert.ElementsMatch(t, []int{2, 4, 6, 8, 10}, vals)
+	assert.GreaterOrEqual(t, time.Since(start), 25*time.Millisecond)
+
+	var total int
+	for _, n := range batches {
+		assert.LessOrEqual(t, n, 2)
+		total += n
+	}
+	assert.Equal(t, 5, total)
+}
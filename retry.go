@@ -0,0 +1,62 @@
+package dataloader
+
+import (
+	"errors"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy controls how a Loader retries failed fetches. Only the keys
+// that failed are retried: if the whole batch fails, every key is retried,
+// but if the fetcher returns KeyErrors, the keys it found are sent to their
+// callers right away. Callers do not see an error until the attempts for
+// their key run out.
+type RetryPolicy struct {
+	// MaxAttempts is the most times a key is fetched, including the first
+	// attempt. Values below 2 mean keys are never retried.
+	MaxAttempts int
+
+	// Backoff is how long to wait before the first retry. The wait doubles
+	// after each retry, up to MaxBackoff, if it is set.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+
+	// Jitter is the fraction, between 0 and 1, of each wait that is random,
+	// so that loaders that failed together do not all retry together.
+	Jitter float64
+
+	// Retryable reports whether an error may succeed if retried. If it is
+	// nil, every error is retried except ErrNotFound, ErrCircuitOpen and
+	// panics.
+	Retryable func(error) bool
+}
+
+func (p *RetryPolicy) retryable(err error, attempt int) bool {
+	if p == nil || attempt >= p.MaxAttempts {
+		return false
+	}
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	var panicErr *PanicError
+	return !errors.Is(err, ErrNotFound) && !errors.Is(err, ErrCircuitOpen) &&
+		!errors.As(err, &panicErr)
+}
+
+// backoff returns how long to wait after the given attempt.
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.Backoff
+	for i := 1; i < attempt; i++ {
+		if p.MaxBackoff > 0 && d >= p.MaxBackoff {
+			break
+		}
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if jitter := time.Duration(float64(d) * min(p.Jitter, 1)); jitter > 0 {
+		d -= rand.N(jitter)
+	}
+	return d
+}
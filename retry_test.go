@@ -0,0 +1,123 @@
+package dataloader_test
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jsocol/dataloader"
+)
+
+func TestRetryFailedKeys(t *testing.T) {
+	rec := &recorder{}
+	errBadGateway := errors.New("502 Bad Gateway")
+	fetcher := func(keys []string) (map[string]int, error) {
+		n := rec.record(keys)
+
+		ret := make(map[string]int, len(keys))
+		kErrs := dataloader.KeyErrors[string]{}
+		for _, k := range keys {
+			if k == "flaky" && n == 1 {
+				kErrs[k] = errBadGateway
+				continue
+			}
+			ret[k] = len(k)
+		}
+		if len(kErrs) > 0 {
+			return ret, kErrs
+		}
+		return ret, nil
+	}
+
+	l := dataloader.New(fetcher, dataloader.WithRetry(dataloader.RetryPolicy{
+		MaxAttempts: 3,
+		Backoff:     time.Millisecond,
+	}))
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	for _, k := range []string{"flaky", "ok"} {
+		go func(k string) {
+			defer wg.Done()
+			v, err := l.Load(k)
+			assert.NoError(t, err)
+			assert.Equal(t, len(k), v)
+		}(k)
+	}
+	wg.Wait()
+
+	assert.Equal(t, [][]string{{"flaky", "ok"}, {"flaky"}}, rec.Batches())
+}
+
+func TestRetryBatchError(t *testing.T) {
+	var calls atomic.Int64
+	fetcher := func(keys []string) (map[string]int, error) {
+		if calls.Add(1) < 3 {
+			return nil, errors.New("502 Bad Gateway")
+		}
+		return map[string]int{"foo": 3}, nil
+	}
+
+	l := dataloader.New(fetcher, dataloader.WithRetry(dataloader.RetryPolicy{
+		MaxAttempts: 3,
+		Backoff:     time.Millisecond,
+		Jitter:      0.5,
+	}))
+
+	v, err := l.Load("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, v)
+	assert.Equal(t, int64(3), calls.Load())
+}
+
+func TestRetryExhausted(t *testing.T) {
+	var calls atomic.Int64
+	errBadGateway := errors.New("502 Bad Gateway")
+	fetcher := func(keys []string) (map[string]int, error) {
+		calls.Add(1)
+		return nil, dataloader.KeyErrors[string]{"foo": errBadGateway}
+	}
+
+	l := dataloader.New(fetcher, dataloader.WithRetry(dataloader.RetryPolicy{
+		MaxAttempts: 2,
+	}))
+
+	_, err := l.Load("foo")
+	assert.ErrorIs(t, err, errBadGateway)
+	assert.Equal(t, int64(2), calls.Load())
+}
+
+func TestRetryable(t *testing.T) {
+	var calls atomic.Int64
+	errBadRequest := errors.New("400 Bad Request")
+	fetcher := func(keys []string) (map[string]int, error) {
+		calls.Add(1)
+		return nil, errBadRequest
+	}
+
+	l := dataloader.New(fetcher, dataloader.WithRetry(dataloader.RetryPolicy{
+		MaxAttempts: 3,
+		Retryable: func(err error) bool {
+			return !errors.Is(err, errBadRequest)
+		},
+	}))
+
+	_, err := l.Load("foo")
+	assert.ErrorIs(t, err, errBadRequest)
+	assert.Equal(t, int64(1), calls.Load())
+
+	calls.Store(0)
+	panicky := dataloader.New(func(keys []string) (map[string]int, error) {
+		calls.Add(1)
+		panic("oops")
+	}, dataloader.WithRetry(dataloader.RetryPolicy{MaxAttempts: 3}))
+
+	_, err = panicky.Load("foo")
+	var panicErr *dataloader.PanicError
+	assert.ErrorAs(t, err, &panicErr)
+	assert.Equal(t, int64(1), calls.Load())
+}
@@ -0,0 +1,146 @@
+package sqlfetch_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// fakeDriver is a database/sql driver that records every query and answers it
+// with a responder supplied by the test.
+type fakeDriver struct {
+	mu       sync.Mutex
+	columns  []string
+	respond  func(query string, args []driver.Value) [][]driver.Value
+	queries  []string
+	prepares atomic.Int64
+}
+
+var driverCount atomic.Int64
+
+func openFake(columns []string, respond func(string, []driver.Value) [][]driver.Value) (*sql.DB, *fakeDriver) {
+	d := &fakeDriver{columns: columns, respond: respond}
+	name := fmt.Sprintf("fake-%d", driverCount.Add(1))
+	sql.Register(name, d)
+	db, err := sql.Open(name, "")
+	if err != nil {
+		panic(err)
+	}
+	return db, d
+}
+
+func (d *fakeDriver) Open(string) (driver.Conn, error) {
+	return &fakeConn{d: d}, nil
+}
+
+func (d *fakeDriver) Queries() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.queries...)
+}
+
+type fakeConn struct {
+	d *fakeDriver
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	c.d.prepares.Add(1)
+	return &fakeStmt{d: c.d, query: query}, nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return nil, fmt.Errorf("transactions not supported")
+}
+
+type fakeStmt struct {
+	d     *fakeDriver
+	query string
+}
+
+func (s *fakeStmt) Close() error {
+	return nil
+}
+
+func (s *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeStmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, fmt.Errorf("exec not supported")
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), namedValues(args))
+}
+
+func (s *fakeStmt) QueryContext(_ context.Context, named []driver.NamedValue) (driver.Rows, error) {
+	args := make([]driver.Value, len(named))
+	for i, nv := range named {
+		args[i] = nv.Value
+	}
+
+	s.d.mu.Lock()
+	s.d.queries = append(s.d.queries, s.query)
+	s.d.mu.Unlock()
+
+	return &fakeRows{columns: s.d.columns, rows: s.d.respond(s.query, args)}, nil
+}
+
+func namedValues(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return named
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.columns
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+// selectWhereIn answers queries by returning every row whose first column is
+// one of the arguments, the way "WHERE key IN (...)" would.
+func selectWhereIn(table [][]driver.Value) func(string, []driver.Value) [][]driver.Value {
+	return func(query string, args []driver.Value) [][]driver.Value {
+		if !strings.Contains(query, " IN ") {
+			return table
+		}
+		var ret [][]driver.Value
+		for _, row := range table {
+			for _, arg := range args {
+				if row[0] == arg {
+					ret = append(ret, row)
+					break
+				}
+			}
+		}
+		return ret
+	}
+}
@@ -0,0 +1,193 @@
+package sqlfetch
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Strategy is how a Fetcher with a composite key builds its queries.
+type Strategy struct {
+	groupBy string
+}
+
+// TupleIn compares all the key columns at once, like
+//
+//	WHERE (tenant_id, id) IN ((?, ?), (?, ?))
+//
+// which is supported by PostgreSQL, MySQL and SQLite, among others.
+var TupleIn = Strategy{}
+
+// GroupBy groups the keys by the value of one key column, and issues one query
+// for each group, like
+//
+//	WHERE tenant_id = ? AND id IN (?, ?)
+//
+// for databases that do not support tuple comparisons. If more than one other
+// key column remains, they are compared with
+//
+//	WHERE a = ? AND ((b = ? AND c = ?) OR (b = ? AND c = ?))
+func GroupBy(column string) Strategy {
+	return Strategy{groupBy: column}
+}
+
+// query is a single statement, with the keys it selects and their arguments.
+type query[K comparable] struct {
+	text string
+	keys []K
+	args []any
+}
+
+// plan splits keys into queries that each stay under the placeholder limit.
+// The number of keys in each query is rounded up to a power of two, repeating
+// the last key to fill the gap, so that only a handful of distinct statements
+// ever need to be prepared.
+func (f *Fetcher[K, V]) plan(keys []K) []query[K] {
+	if f.config.strategy.groupBy == "" || len(f.keyColumns) == 1 {
+		return f.planChunks(keys, nil, f.keyColumns, f.keyArgs)
+	}
+
+	g := slices.Index(f.keyColumns, f.config.strategy.groupBy)
+	if g < 0 {
+		panic(fmt.Sprintf("sqlfetch: cannot group by %s, it is not a key column", f.config.strategy.groupBy))
+	}
+
+	rest := slices.Delete(slices.Clone(f.keyColumns), g, g+1)
+	restArgs := func(k K) []any {
+		return slices.Delete(f.keyArgs(k), g, g+1)
+	}
+
+	// group keys by the value of the column, in the order they were seen
+	var values []any
+	groups := make(map[any][]K)
+	for _, k := range keys {
+		v := f.keyArgs(k)[g]
+		if _, ok := groups[v]; !ok {
+			values = append(values, v)
+		}
+		groups[v] = append(groups[v], k)
+	}
+
+	var ret []query[K]
+	for _, v := range values {
+		eq := &equals{column: f.config.strategy.groupBy, value: v}
+		ret = append(ret, f.planChunks(groups[v], eq, rest, restArgs)...)
+	}
+	return ret
+}
+
+type equals struct {
+	column string
+	value  any
+}
+
+func (f *Fetcher[K, V]) planChunks(keys []K, eq *equals, columns []string, args func(K) []any) []query[K] {
+	limit := f.config.maxParams
+	if eq != nil {
+		limit--
+	}
+	limit = max(limit/len(columns), 1)
+
+	var ret []query[K]
+	for len(keys) > 0 {
+		n := min(len(keys), limit)
+		chunk := keys[:n]
+		keys = keys[n:]
+
+		size := 1
+		for size < n {
+			size *= 2
+		}
+		size = min(size, limit)
+
+		b := &builder{placeholder: f.config.placeholder}
+		b.WriteString(f.selectFrom)
+		if eq != nil {
+			b.WriteString(eq.column)
+			b.WriteString(" = ")
+			b.param(eq.value)
+			b.WriteString(" AND ")
+		}
+		keyArgs := func(i int) []any {
+			return args(chunk[min(i, n-1)])
+		}
+		if eq != nil && len(columns) > 1 {
+			b.anyOf(columns, size, keyArgs)
+		} else {
+			b.in(columns, size, keyArgs)
+		}
+
+		ret = append(ret, query[K]{
+			text: b.String(),
+			keys: chunk,
+			args: b.args,
+		})
+	}
+	return ret
+}
+
+// builder writes a query while numbering its placeholders.
+type builder struct {
+	strings.Builder
+	placeholder Placeholder
+	args        []any
+}
+
+func (b *builder) param(v any) {
+	b.args = append(b.args, v)
+	b.WriteString(b.placeholder(len(b.args)))
+}
+
+// in writes "col IN (?, ?)" for one column, and "(a, b) IN ((?, ?), (?, ?))"
+// for several.
+func (b *builder) in(columns []string, n int, args func(i int) []any) {
+	if len(columns) == 1 {
+		b.WriteString(columns[0])
+	} else {
+		b.WriteByte('(')
+		b.WriteString(strings.Join(columns, ", "))
+		b.WriteByte(')')
+	}
+	b.WriteString(" IN (")
+	for i := range n {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		vs := args(i)
+		if len(vs) > 1 {
+			b.WriteByte('(')
+		}
+		for j, v := range vs {
+			if j > 0 {
+				b.WriteString(", ")
+			}
+			b.param(v)
+		}
+		if len(vs) > 1 {
+			b.WriteByte(')')
+		}
+	}
+	b.WriteByte(')')
+}
+
+// anyOf writes "((a = ? AND b = ?) OR (a = ? AND b = ?))", which is
+// equivalent to a tuple IN but works everywhere.
+func (b *builder) anyOf(columns []string, n int, args func(i int) []any) {
+	b.WriteByte('(')
+	for i := range n {
+		if i > 0 {
+			b.WriteString(" OR ")
+		}
+		b.WriteByte('(')
+		for j, v := range args(i) {
+			if j > 0 {
+				b.WriteString(" AND ")
+			}
+			b.WriteString(columns[j])
+			b.WriteString(" = ")
+			b.param(v)
+		}
+		b.WriteByte(')')
+	}
+	b.WriteByte(')')
+}
@@ -0,0 +1,114 @@
+package sqlfetch_test
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jsocol/dataloader/sqlfetch"
+)
+
+type tenantKey struct {
+	tenant int64
+	id     string
+}
+
+var tenantBooks = [][]driver.Value{
+	{int64(1), "urn:isbn:978-1098149482", "The Engineering Executive's Primer"},
+	{int64(1), "urn:isbn:978-1736417911", "Staff Engineer"},
+	{int64(2), "urn:isbn:978-1098149482", "The Engineering Executive's Primer (2nd copy)"},
+}
+
+// selectTenantBooks answers both kinds of query with the rows matching the
+// (tenant_id, id) pairs in its arguments.
+func selectTenantBooks(query string, args []driver.Value) [][]driver.Value {
+	want := make(map[tenantKey]bool)
+	if !strings.Contains(query, "tenant_id = ") {
+		for i := 0; i < len(args); i += 2 {
+			want[tenantKey{tenant: args[i].(int64), id: args[i+1].(string)}] = true
+		}
+	} else {
+		for _, id := range args[1:] {
+			want[tenantKey{tenant: args[0].(int64), id: id.(string)}] = true
+		}
+	}
+
+	var ret [][]driver.Value
+	for _, row := range tenantBooks {
+		if want[tenantKey{tenant: row[0].(int64), id: row[1].(string)}] {
+			ret = append(ret, row)
+		}
+	}
+	return ret
+}
+
+func scanTenantBook(rows *sql.Rows) (tenantKey, string, error) {
+	var k tenantKey
+	var title string
+	err := rows.Scan(&k.tenant, &k.id, &title)
+	return k, title, err
+}
+
+func tenantArgs(k tenantKey) []any {
+	return []any{k.tenant, k.id}
+}
+
+func TestCompositeTupleIn(t *testing.T) {
+	db, d := openFake([]string{"tenant_id", "id", "title"}, selectTenantBooks)
+	defer db.Close()
+
+	f := sqlfetch.NewComposite(db, "books", []string{"tenant_id", "id"}, tenantArgs,
+		[]string{"tenant_id", "id", "title"}, scanTenantBook,
+		sqlfetch.WithPlaceholder(sqlfetch.Dollar),
+	)
+	defer f.Close()
+
+	got, err := f.Fetch([]tenantKey{
+		{tenant: 1, id: "urn:isbn:978-1098149482"},
+		{tenant: 2, id: "urn:isbn:978-1098149482"},
+		{tenant: 2, id: "urn:isbn:978-1736417911"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[tenantKey]string{
+		{tenant: 1, id: "urn:isbn:978-1098149482"}: "The Engineering Executive's Primer",
+		{tenant: 2, id: "urn:isbn:978-1098149482"}: "The Engineering Executive's Primer (2nd copy)",
+	}, got)
+
+	assert.Equal(t, []string{
+		"SELECT tenant_id, id, title FROM books WHERE (tenant_id, id) IN (($1, $2), ($3, $4), ($5, $6), ($7, $8))",
+	}, d.Queries())
+}
+
+func TestCompositeGroupBy(t *testing.T) {
+	db, d := openFake([]string{"tenant_id", "id", "title"}, selectTenantBooks)
+	defer db.Close()
+
+	f := sqlfetch.NewComposite(db, "books", []string{"tenant_id", "id"}, tenantArgs,
+		[]string{"tenant_id", "id", "title"}, scanTenantBook,
+		sqlfetch.WithStrategy(sqlfetch.GroupBy("tenant_id")),
+		sqlfetch.WithMaxParams(3),
+	)
+	defer f.Close()
+
+	got, err := f.Fetch([]tenantKey{
+		{tenant: 1, id: "urn:isbn:978-1098149482"},
+		{tenant: 2, id: "urn:isbn:978-1098149482"},
+		{tenant: 1, id: "urn:isbn:978-1736417911"},
+		{tenant: 1, id: "urn:isbn:fake-book"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[tenantKey]string{
+		{tenant: 1, id: "urn:isbn:978-1098149482"}: "The Engineering Executive's Primer",
+		{tenant: 1, id: "urn:isbn:978-1736417911"}: "Staff Engineer",
+		{tenant: 2, id: "urn:isbn:978-1098149482"}: "The Engineering Executive's Primer (2nd copy)",
+	}, got)
+
+	assert.Equal(t, []string{
+		"SELECT tenant_id, id, title FROM books WHERE tenant_id = ? AND id IN (?, ?)",
+		"SELECT tenant_id, id, title FROM books WHERE tenant_id = ? AND id IN (?)",
+		"SELECT tenant_id, id, title FROM books WHERE tenant_id = ? AND id IN (?)",
+	}, d.Queries())
+}
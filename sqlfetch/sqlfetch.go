@@ -0,0 +1,205 @@
+// Package sqlfetch builds dataloader fetchers that select rows from a
+// database/sql table with a WHERE ... IN (...) query.
+package sqlfetch
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// DefaultMaxParams is the number of placeholders allowed in a single query
+// when WithMaxParams is not used. It is SQLite's default limit, which is also
+// low enough for every other common database.
+const DefaultMaxParams = 999
+
+// Preparer is implemented by *sql.DB, *sql.Tx and *sql.Conn.
+type Preparer interface {
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+// Scanner reads the current row into a key and a value. The row has the
+// columns given to New, in order.
+type Scanner[K comparable, V any] func(*sql.Rows) (K, V, error)
+
+// Placeholder returns the bind parameter for the i'th argument of a query,
+// counting from 1.
+type Placeholder func(i int) string
+
+// Question is the placeholder style used by MySQL and SQLite.
+func Question(int) string {
+	return "?"
+}
+
+// Dollar is the placeholder style used by PostgreSQL.
+func Dollar(i int) string {
+	return fmt.Sprintf("$%d", i)
+}
+
+type config struct {
+	maxParams   int
+	placeholder Placeholder
+	strategy    Strategy
+	logger      *slog.Logger
+}
+
+type Option func(*config)
+
+// WithMaxParams sets the maximum number of placeholders in a query. Larger
+// batches of keys are split into several queries. For example, PostgreSQL
+// allows 65535.
+func WithMaxParams(n int) Option {
+	return func(c *config) {
+		c.maxParams = n
+	}
+}
+
+// WithPlaceholder sets the bind parameter style. The default is Question.
+func WithPlaceholder(p Placeholder) Option {
+	return func(c *config) {
+		c.placeholder = p
+	}
+}
+
+// WithStrategy sets how a Fetcher created by NewComposite queries for keys.
+// The default is TupleIn.
+func WithStrategy(s Strategy) Option {
+	return func(c *config) {
+		c.strategy = s
+	}
+}
+
+// WithLogger logs each query and its arguments at debug level.
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *config) {
+		c.logger = logger
+	}
+}
+
+// Fetcher selects rows by key. Its Fetch method can be passed to
+// dataloader.New, and its FetchContext method to dataloader.NewContext.
+type Fetcher[K comparable, V any] struct {
+	db         Preparer
+	selectFrom string
+	keyColumns []string
+	keyArgs    func(K) []any
+	scan       Scanner[K, V]
+	config     config
+
+	mu    sync.Mutex
+	stmts map[string]*sql.Stmt
+}
+
+// New creates a Fetcher that selects columns from table where keyColumn is
+// one of the requested keys. The table and column names are used in the query
+// as-is, so they must not come from untrusted input.
+func New[K comparable, V any](db Preparer, table, keyColumn string, columns []string, scan Scanner[K, V], opts ...Option) *Fetcher[K, V] {
+	keyArgs := func(k K) []any {
+		return []any{k}
+	}
+	return NewComposite(db, table, []string{keyColumn}, keyArgs, columns, scan, opts...)
+}
+
+// NewComposite creates a Fetcher for keys that span several columns, like
+// (tenant_id, id). keyArgs returns the value of each of the keyColumns for a
+// key, in order. How the keys are queried depends on the Strategy.
+func NewComposite[K comparable, V any](db Preparer, table string, keyColumns []string, keyArgs func(K) []any, columns []string, scan Scanner[K, V], opts ...Option) *Fetcher[K, V] {
+	c := config{
+		maxParams:   DefaultMaxParams,
+		placeholder: Question,
+		strategy:    TupleIn,
+	}
+	for _, o := range opts {
+		o(&c)
+	}
+
+	return &Fetcher[K, V]{
+		db:         db,
+		selectFrom: fmt.Sprintf("SELECT %s FROM %s WHERE ", strings.Join(columns, ", "), table),
+		keyColumns: keyColumns,
+		keyArgs:    keyArgs,
+		scan:       scan,
+		config:     c,
+		stmts:      make(map[string]*sql.Stmt),
+	}
+}
+
+// Fetch selects the rows for keys, using as many queries as needed to stay
+// under the placeholder limit. An empty list of keys does not query the
+// database at all.
+func (f *Fetcher[K, V]) Fetch(keys []K) (map[K]V, error) {
+	return f.FetchContext(context.Background(), keys)
+}
+
+// FetchContext is like Fetch, but runs the queries with ctx, so that they are
+// cancelled along with the batch.
+func (f *Fetcher[K, V]) FetchContext(ctx context.Context, keys []K) (map[K]V, error) {
+	ret := make(map[K]V, len(keys))
+	for _, q := range f.plan(keys) {
+		if err := f.query(ctx, q, ret); err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+// Close closes all the prepared statements.
+func (f *Fetcher[K, V]) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var err error
+	for text, stmt := range f.stmts {
+		if cErr := stmt.Close(); cErr != nil && err == nil {
+			err = cErr
+		}
+		delete(f.stmts, text)
+	}
+	return err
+}
+
+func (f *Fetcher[K, V]) query(ctx context.Context, q query[K], ret map[K]V) error {
+	stmt, err := f.stmt(ctx, q.text)
+	if err != nil {
+		return err
+	}
+
+	if f.config.logger != nil {
+		f.config.logger.DebugContext(ctx, "SELECT", "keys", q.keys, "query", q.text, "args", q.args)
+	}
+
+	rows, err := stmt.QueryContext(ctx, q.args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		k, v, err := f.scan(rows)
+		if err != nil {
+			return err
+		}
+		ret[k] = v
+	}
+
+	return rows.Err()
+}
+
+func (f *Fetcher[K, V]) stmt(ctx context.Context, text string) (*sql.Stmt, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if stmt, ok := f.stmts[text]; ok {
+		return stmt, nil
+	}
+
+	stmt, err := f.db.PrepareContext(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+	f.stmts[text] = stmt
+	return stmt, nil
+}
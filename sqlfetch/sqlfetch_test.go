@@ -0,0 +1,104 @@
+package sqlfetch_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jsocol/dataloader/sqlfetch"
+)
+
+var books = [][]driver.Value{
+	{"urn:isbn:978-1098149482", "The Engineering Executive's Primer"},
+	{"urn:isbn:978-1736417911", "Staff Engineer"},
+	{"urn:isbn:978-1098118730", "The Staff Engineer's Path"},
+	{"urn:isbn:978-1491973899", "Designing Distributed Systems"},
+	{"urn:isbn:978-0786965601", "Player's Handbook"},
+}
+
+func scanTitle(rows *sql.Rows) (string, string, error) {
+	var id, title string
+	err := rows.Scan(&id, &title)
+	return id, title, err
+}
+
+func TestFetch(t *testing.T) {
+	db, d := openFake([]string{"id", "title"}, selectWhereIn(books))
+	defer db.Close()
+
+	f := sqlfetch.New(db, "books", "id", []string{"id", "title"}, scanTitle)
+	defer f.Close()
+
+	got, err := f.Fetch([]string{"urn:isbn:978-1098118730", "urn:isbn:978-0786965601", "urn:isbn:fake-book"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"urn:isbn:978-1098118730": "The Staff Engineer's Path",
+		"urn:isbn:978-0786965601": "Player's Handbook",
+	}, got)
+
+	assert.Equal(t, []string{
+		"SELECT id, title FROM books WHERE id IN (?, ?, ?, ?)",
+	}, d.Queries())
+}
+
+func TestFetchContext(t *testing.T) {
+	db, d := openFake([]string{"id", "title"}, selectWhereIn(books))
+	defer db.Close()
+
+	f := sqlfetch.New(db, "books", "id", []string{"id", "title"}, scanTitle)
+	defer f.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := f.FetchContext(ctx, []string{"urn:isbn:978-1098118730"})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, d.Queries())
+}
+
+func TestFetchNoKeys(t *testing.T) {
+	db, d := openFake([]string{"id", "title"}, selectWhereIn(books))
+	defer db.Close()
+
+	f := sqlfetch.New(db, "books", "id", []string{"id", "title"}, scanTitle)
+	defer f.Close()
+
+	got, err := f.Fetch(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, got)
+	assert.Empty(t, d.Queries())
+}
+
+func TestFetchChunks(t *testing.T) {
+	db, d := openFake([]string{"id", "title"}, selectWhereIn(books))
+	defer db.Close()
+
+	f := sqlfetch.New(db, "books", "id", []string{"id", "title"}, scanTitle,
+		sqlfetch.WithMaxParams(2),
+		sqlfetch.WithPlaceholder(sqlfetch.Dollar),
+	)
+	defer f.Close()
+
+	keys := []string{
+		"urn:isbn:978-1098149482",
+		"urn:isbn:978-1736417911",
+		"urn:isbn:978-1098118730",
+		"urn:isbn:978-1491973899",
+		"urn:isbn:978-0786965601",
+	}
+	got, err := f.Fetch(keys)
+	assert.NoError(t, err)
+	assert.Len(t, got, 5)
+
+	assert.Equal(t, []string{
+		"SELECT id, title FROM books WHERE id IN ($1, $2)",
+		"SELECT id, title FROM books WHERE id IN ($1, $2)",
+		"SELECT id, title FROM books WHERE id IN ($1)",
+	}, d.Queries())
+
+	// one statement for each distinct number of placeholders
+	assert.Equal(t, int64(2), d.prepares.Load())
+}
@@ -0,0 +1,88 @@
+package sqlfetch
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// NewStruct creates a Fetcher for V, which must be a struct or a pointer to a
+// struct. The select list is built from the `sql` tags on the exported fields
+// of V, and each row is scanned into those fields. keyColumn must be the tag
+// of a field with type K, which is used as the key of the result map.
+//
+// For example:
+//
+//	type book struct {
+//		ID    string `sql:"id"`
+//		Title string `sql:"title"`
+//	}
+//
+//	f := sqlfetch.NewStruct[string, *book](db, "books", "id")
+//
+// NewStruct panics if V or keyColumn are not valid.
+func NewStruct[K comparable, V any](db Preparer, table, keyColumn string, opts ...Option) *Fetcher[K, V] {
+	columns, scan := structScanner[K, V](keyColumn)
+	return New(db, table, keyColumn, columns, scan, opts...)
+}
+
+func structScanner[K comparable, V any](keyColumn string) ([]string, Scanner[K, V]) {
+	t := reflect.TypeFor[V]()
+	ptr := t.Kind() == reflect.Pointer
+	if ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("sqlfetch: %v is not a struct or a pointer to a struct", reflect.TypeFor[V]()))
+	}
+
+	var columns []string
+	var fields []int
+	keyField := -1
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("sql"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		if name == keyColumn {
+			if !f.Type.AssignableTo(reflect.TypeFor[K]()) {
+				panic(fmt.Sprintf("sqlfetch: key field %s has type %v, not %v", f.Name, f.Type, reflect.TypeFor[K]()))
+			}
+			keyField = i
+		}
+		columns = append(columns, name)
+		fields = append(fields, i)
+	}
+	if keyField < 0 {
+		panic(fmt.Sprintf("sqlfetch: %v has no field tagged `sql:%q`", t, keyColumn))
+	}
+
+	scan := func(rows *sql.Rows) (K, V, error) {
+		var k K
+		var v V
+
+		pv := reflect.New(t)
+		dest := make([]any, len(fields))
+		for i, idx := range fields {
+			dest[i] = pv.Elem().Field(idx).Addr().Interface()
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return k, v, err
+		}
+
+		k = pv.Elem().Field(keyField).Interface().(K)
+		if ptr {
+			v = pv.Interface().(V)
+		} else {
+			v = pv.Elem().Interface().(V)
+		}
+		return k, v, nil
+	}
+
+	return columns, scan
+}
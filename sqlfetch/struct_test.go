@@ -0,0 +1,63 @@
+package sqlfetch_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jsocol/dataloader/sqlfetch"
+)
+
+type bookRecord struct {
+	ID       string `sql:"id"`
+	Title    string `sql:"title"`
+	Internal int
+	ignored  string `sql:"ignored"`
+}
+
+func TestNewStruct(t *testing.T) {
+	db, d := openFake([]string{"id", "title"}, selectWhereIn(books))
+	defer db.Close()
+
+	f := sqlfetch.NewStruct[string, *bookRecord](db, "books", "id")
+	defer f.Close()
+
+	got, err := f.Fetch([]string{"urn:isbn:978-1098118730", "urn:isbn:fake-book"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]*bookRecord{
+		"urn:isbn:978-1098118730": {ID: "urn:isbn:978-1098118730", Title: "The Staff Engineer's Path"},
+	}, got)
+
+	assert.Equal(t, []string{
+		"SELECT id, title FROM books WHERE id IN (?, ?)",
+	}, d.Queries())
+}
+
+func TestNewStructValue(t *testing.T) {
+	db, _ := openFake([]string{"id", "title"}, selectWhereIn(books))
+	defer db.Close()
+
+	f := sqlfetch.NewStruct[string, bookRecord](db, "books", "id")
+	defer f.Close()
+
+	got, err := f.Fetch([]string{"urn:isbn:978-0786965601"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bookRecord{
+		"urn:isbn:978-0786965601": {ID: "urn:isbn:978-0786965601", Title: "Player's Handbook"},
+	}, got)
+}
+
+func TestNewStructInvalid(t *testing.T) {
+	db, _ := openFake(nil, selectWhereIn(nil))
+	defer db.Close()
+
+	assert.PanicsWithValue(t, "sqlfetch: sqlfetch_test.bookRecord has no field tagged `sql:\"isbn\"`", func() {
+		sqlfetch.NewStruct[string, bookRecord](db, "books", "isbn")
+	})
+	assert.PanicsWithValue(t, "sqlfetch: key field ID has type string, not int", func() {
+		sqlfetch.NewStruct[int, bookRecord](db, "books", "id")
+	})
+	assert.PanicsWithValue(t, "sqlfetch: string is not a struct or a pointer to a struct", func() {
+		sqlfetch.NewStruct[string, string](db, "books", "id")
+	})
+}